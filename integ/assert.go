@@ -3,61 +3,130 @@ package integ
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"testing"
-
-	"github.com/hashicorp/go-multierror"
-	"github.com/jmespath/go-jmespath"
+	"time"
 )
 
 type Assertion struct {
-	Path           string  // JMESPath of the value to assert
-	Exists         bool    // Whether the value should exist, `true` if ExpectedRegexp is provided
-	ExpectedRegexp *string // Regexp to match the value against
+	Path           string     // JMESPath of the value to assert
+	Exists         bool       // Whether the value should exist, `true` if ExpectedRegexp or Matcher (other than Absent) is provided
+	ExpectedRegexp *string    // Regexp to match the value against
+	Matcher        Matcher    // Matcher to match the value against, combined with ExpectedRegexp if both are provided
+	Quantifier     Quantifier // How many elements of an array value must match (Any, All, None, Exactly(n), AtLeast(n)), the zero value matches the value as a whole
 }
 
 // ref https://github.com/aws/aws-cdk/blob/v2.161.1/packages/%40aws-cdk/integ-tests-alpha/lib/assertions/sdk.ts
 
-// Assert asserts the given input against the provided assertions.
-// Fails the test if any assertion fails.
+// Assert asserts the given input against the provided assertions and records the results in the test reports
+// (see ReporterFor). Fails the test if any assertion fails.
 func Assert(t *testing.T, input any, assertions []Assertion) {
-	if err := AssertE(input, assertions); err != nil {
+	results, err := EvaluateE(input, assertions)
+	if err == nil {
+		ReporterFor(t).Record("", results...)
+		err = ResultsError(results)
+	}
+	if err != nil {
 		t.Errorf("failed assertions: %v", err)
 	}
 }
 
 // AssertE asserts the given input against the provided assertions and returns an error if any assertion fails.
+//
+// The input may be any value, it is normalized to its JSON representation before searching (see Normalize).
 func AssertE(input any, assertions []Assertion) error {
-	var combinedErr error
+	results, err := EvaluateE(input, assertions)
+	if err != nil {
+		return err
+	}
+	return ResultsError(results)
+}
+
+// EvaluateE evaluates the provided assertions against the given input and returns a result for every assertion.
+// Returns an error only if the input cannot be normalized.
+func EvaluateE(input any, assertions []Assertion) ([]AssertionResult, error) {
+	input, err := Normalize(input)
+	if err != nil {
+		return nil, fmt.Errorf("error normalizing input: %v", err)
+	}
+	results := make([]AssertionResult, 0, len(assertions))
 	for _, a := range assertions {
-		if a.Path == "" {
-			combinedErr = multierror.Append(combinedErr, fmt.Errorf("path cannot be empty"))
-			continue
+		results = append(results, a.evaluate(input))
+	}
+	return results, nil
+}
+
+// evaluate asserts the normalized input and records the outcome
+func (a Assertion) evaluate(input any) AssertionResult {
+	start := time.Now()
+	matcher := a.matcher()
+	result := AssertionResult{
+		Path:       a.Path,
+		Quantifier: a.Quantifier.String(),
+		Expected:   expectedValue(matcher),
+	}
+	if matcher != nil {
+		result.Matcher = matcher.String()
+	} else if a.Exists {
+		result.Matcher = "exists()"
+	}
+	actual, err := a.check(input, matcher)
+	result.Actual = actual
+	result.Passed = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.Duration = time.Since(start)
+	return result
+}
+
+// check returns the value found at the assertion path and an error if the assertion fails
+func (a Assertion) check(input any, matcher Matcher) (any, error) {
+	if a.Path == "" {
+		return nil, fmt.Errorf("path cannot be empty")
+	}
+	p, err := CompileJMESPath(a.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling JMESPath '%s': '%v'", a.Path, err)
+	}
+	value, err := p.Search(input)
+	if err != nil || value == nil {
+		if !a.Exists && (matcher == nil || isAbsent(matcher)) {
+			// If the path does not exist or is nil and the value should not exist, we consider this a success
+			return nil, nil
 		}
-		p, err := jmespath.Compile(a.Path)
 		if err != nil {
-			combinedErr = multierror.Append(combinedErr, fmt.Errorf("error compiling JMESPath '%s': '%v'", a.Path, err))
-			continue
+			return nil, fmt.Errorf("error searching JMESPath '%s': %v", a.Path, err)
 		}
-		value, err := p.Search(input)
-		if err != nil || value == nil {
-			if !a.Exists && a.ExpectedRegexp == nil {
-				// If the path does not exist or is nil and the value should not exist, we consider this a success
-				continue
-			}
-			if err != nil {
-				combinedErr = multierror.Append(combinedErr, fmt.Errorf("error searching JMESPath '%s': %v", a.Path, err))
-			} else {
-				combinedErr = multierror.Append(combinedErr, fmt.Errorf("value at '%s' is nil", a.Path))
-			}
-			continue
+		return nil, fmt.Errorf("value at '%s' is nil", a.Path)
+	}
+	if !a.Quantifier.IsZero() {
+		if matcher == nil {
+			return value, fmt.Errorf("quantifier %s at '%s' requires ExpectedRegexp or Matcher", a.Quantifier, a.Path)
 		}
-		if a.ExpectedRegexp != nil {
-			if err := assertRegexp(value, *a.ExpectedRegexp); err != nil {
-				combinedErr = multierror.Append(combinedErr, fmt.Errorf("error asserting value at '%s': %v", a.Path, err))
-			}
+		if err := a.Quantifier.Match(value, matcher); err != nil {
+			return value, fmt.Errorf("error asserting value at '%s': %v", a.Path, err)
 		}
+		return value, nil
 	}
-	return combinedErr
+	if matcher != nil {
+		if err := matcher.Match(value); err != nil {
+			return value, fmt.Errorf("error asserting value at '%s': %v", a.Path, err)
+		}
+	}
+	return value, nil
+}
+
+// matcher returns the Matcher for the assertion, or nil if only existence is asserted
+func (a Assertion) matcher() Matcher {
+	if a.ExpectedRegexp == nil {
+		return a.Matcher
+	}
+	re := Regexp(*a.ExpectedRegexp)
+	if a.Matcher == nil {
+		return re
+	}
+	return And(re, a.Matcher)
 }
 
 func assertRegexp(value any, expectedRegexp string) error {
@@ -69,17 +138,25 @@ func assertRegexp(value any, expectedRegexp string) error {
 	switch v := value.(type) {
 	case []interface{}:
 		for _, elem := range v {
-			elemStr := fmt.Sprintf("%v", elem)
+			elemStr := stringify(elem)
 			if re.MatchString(elemStr) {
 				return nil // Success if any element matches
 			}
 		}
 		return fmt.Errorf("none of the values '%v' match regexp '%s'", v, expectedRegexp)
 	default:
-		valueStr := fmt.Sprintf("%v", value)
+		valueStr := stringify(value)
 		if !re.MatchString(valueStr) {
 			return fmt.Errorf("value '%s' does not match regexp '%s'", valueStr, expectedRegexp)
 		}
 		return nil
 	}
 }
+
+// stringify formats a value for regexp matching, numbers are never formatted with an exponent
+func stringify(value any) string {
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", value)
+}
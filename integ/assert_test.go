@@ -17,11 +17,11 @@ func TestAssert_Success(t *testing.T) {
 			Path:           "status",
 			ExpectedRegexp: strPtr(`^\d+$`),
 		},
-		// // lower is not supported by go-jmespath
-		// {
-		// 	Path:           "request.headers.*[lower(@) == 'host']",
-		// 	ExpectedRegexp: strPtr("(?i)www\\.example\\.com"),
-		// },
+		{
+			// lower is registered as custom JMESPath function
+			Path:           "keys(request.headers)[?lower(@) == 'host']",
+			ExpectedRegexp: strPtr("^host$"),
+		},
 		{
 			Path:           "request.querystring.test.value",
 			ExpectedRegexp: strPtr("true"),
@@ -140,7 +140,7 @@ func TestAssert_ValueIsNil(t *testing.T) {
 }
 
 // Test data, example Edge Function output...
-// NOTE: go-jmespath fails on map[]interface{} unless we use a fork
+// NOTE: typed maps, slices and pointers are normalized to JSON before searching
 var testObject = map[string]any{
 	"status": 200,
 	"request": map[string]any{
@@ -0,0 +1,288 @@
+package integ
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// expectSuffixes are the suffixes of assertion files paired with `*.json` test events, in order of preference.
+var expectSuffixes = []string{".expect.yaml", ".expect.yml", ".expect.json"}
+
+// AssertionSpec is the declarative form of an Assertion in YAML or JSON assertion files:
+//
+//	# index.expect.yaml
+//	- path: request.uri
+//	  match:
+//	    regexp: ^/index.html$
+//	- path: request.headers.authorization
+//	  exists: false
+//	- path: response.statusCode
+//	  match:
+//	    and: [{type: number}, {between: [400, 499]}]
+type AssertionSpec struct {
+	Path       string      `yaml:"path"`       // JMESPath of the value to assert
+	Exists     *bool       `yaml:"exists"`     // `true` requires the value to exist, `false` requires the value to be absent
+	Match      MatcherSpec `yaml:"match"`      // Matcher to match the value against
+	Quantifier string      `yaml:"quantifier"` // How many array elements must match: any, all, none, exactly(n) or atLeast(n)
+}
+
+// MatcherSpec is the declarative form of a Matcher, a map with a single key naming the matcher:
+//
+//	regexp: <pattern>            equals: <value>              exact: <value>
+//	gt: <number>                 lt: <number>                 between: [<min>, <max>]
+//	type: <string|number|...>    prefix: <string>             suffix: <string>
+//	objectLike: <object>         arrayWith: <array>           absent: true
+//	anyValue: true               and: [<matcher>, ...]        or: [<matcher>, ...]
+//	not: <matcher>
+type MatcherSpec map[string]any
+
+// LoadAssertions loads assertions from a YAML or JSON file holding a list of AssertionSpec.
+func LoadAssertions(path string) ([]Assertion, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var specs []AssertionSpec
+	// YAML is a superset of JSON
+	if err := yaml.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("error parsing assertions file %s: %v", path, err)
+	}
+	assertions := make([]Assertion, 0, len(specs))
+	for i, spec := range specs {
+		a, err := spec.Assertion()
+		if err != nil {
+			return nil, fmt.Errorf("invalid assertion %d in %s: %v", i, path, err)
+		}
+		assertions = append(assertions, a)
+	}
+	return assertions, nil
+}
+
+// Assertion converts the spec to an Assertion.
+func (s AssertionSpec) Assertion() (Assertion, error) {
+	if s.Path == "" {
+		return Assertion{}, fmt.Errorf("path cannot be empty")
+	}
+	a := Assertion{Path: s.Path}
+	if s.Match != nil {
+		m, err := s.Match.Matcher()
+		if err != nil {
+			return Assertion{}, fmt.Errorf("path '%s': %v", s.Path, err)
+		}
+		a.Matcher = m
+	}
+	if s.Quantifier != "" {
+		q, err := ParseQuantifier(s.Quantifier)
+		if err != nil {
+			return Assertion{}, fmt.Errorf("path '%s': %v", s.Path, err)
+		}
+		a.Quantifier = q
+	}
+	if s.Exists != nil {
+		if *s.Exists {
+			a.Exists = true
+		} else if a.Matcher == nil {
+			a.Matcher = Absent()
+		} else {
+			return Assertion{}, fmt.Errorf("path '%s': exists: false cannot be combined with match", s.Path)
+		}
+	}
+	return a, nil
+}
+
+// Matcher converts the spec to a Matcher.
+func (s MatcherSpec) Matcher() (Matcher, error) {
+	if len(s) != 1 {
+		return nil, fmt.Errorf("matcher must have exactly one key, got %d", len(s))
+	}
+	for name, arg := range s {
+		return buildMatcher(name, arg)
+	}
+	return nil, nil
+}
+
+func buildMatcher(name string, arg any) (Matcher, error) {
+	switch name {
+	case "regexp":
+		s, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("regexp expects a string, got %T", arg)
+		}
+		return Regexp(s), nil
+	case "equals":
+		return Equals(arg), nil
+	case "exact":
+		return Exact(arg), nil
+	case "objectLike":
+		return ObjectLike(arg), nil
+	case "arrayWith":
+		return ArrayWith(arg), nil
+	case "gt", "lt":
+		n, ok := toNumber(arg)
+		if !ok {
+			return nil, fmt.Errorf("%s expects a number, got %T", name, arg)
+		}
+		if name == "gt" {
+			return GreaterThan(n), nil
+		}
+		return LessThan(n), nil
+	case "between":
+		bounds, ok := arg.([]any)
+		if !ok || len(bounds) != 2 {
+			return nil, fmt.Errorf("between expects [min, max]")
+		}
+		lo, okLo := toNumber(bounds[0])
+		hi, okHi := toNumber(bounds[1])
+		if !okLo || !okHi {
+			return nil, fmt.Errorf("between expects numbers, got %v", bounds)
+		}
+		return Between(lo, hi), nil
+	case "type":
+		s, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("type expects a string, got %T", arg)
+		}
+		return IsType(JSONType(s)), nil
+	case "prefix", "suffix":
+		s, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s expects a string, got %T", name, arg)
+		}
+		if name == "prefix" {
+			return HasPrefix(s), nil
+		}
+		return HasSuffix(s), nil
+	case "absent":
+		return Absent(), nil
+	case "anyValue":
+		return AnyValue(), nil
+	case "and", "or":
+		specs, ok := arg.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s expects a list of matchers", name)
+		}
+		matchers := make([]Matcher, 0, len(specs))
+		for _, spec := range specs {
+			m, err := toMatcher(spec)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+			matchers = append(matchers, m)
+		}
+		if name == "and" {
+			return And(matchers...), nil
+		}
+		return Or(matchers...), nil
+	case "not":
+		m, err := toMatcher(arg)
+		if err != nil {
+			return nil, fmt.Errorf("not: %v", err)
+		}
+		return Not(m), nil
+	default:
+		return nil, fmt.Errorf("unknown matcher '%s'", name)
+	}
+}
+
+func toMatcher(spec any) (Matcher, error) {
+	switch m := spec.(type) {
+	case MatcherSpec:
+		// yaml.v3 decodes nested maps with the type of the parent map
+		return m.Matcher()
+	case map[string]any:
+		return MatcherSpec(m).Matcher()
+	default:
+		return nil, fmt.Errorf("expected a matcher, got %T", spec)
+	}
+}
+
+func toNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// EventHandler invokes the system under test with the test event at eventPath and returns the output to assert.
+type EventHandler func(t *testing.T, eventPath string) (any, error)
+
+// RunEventAssertions runs a subtest for each `*.json` test event in dir, paired with its `*.expect.yaml` assertions file.
+//
+// The output returned by the handler is asserted against the loaded assertions,
+// events without assertions file fail the test. Results are logged and written to the test reports (see Reporter).
+func RunEventAssertions(t *testing.T, dir string, handler EventHandler) {
+	events, err := findTestEvents(dir)
+	if err != nil {
+		t.Fatalf("failed to list test events in %s: %v", dir, err)
+	}
+	if len(events) == 0 {
+		t.Fatalf("no test events found in %s", dir)
+	}
+	reporter := ReporterFor(t)
+	for _, eventPath := range events {
+		name := strings.TrimSuffix(filepath.Base(eventPath), ".json")
+		t.Run(name, func(t *testing.T) {
+			expectPath, ok := findExpectFile(eventPath)
+			if !ok {
+				t.Fatalf("no assertions file found for %s (expected one of %s)", eventPath, strings.Join(expectSuffixes, ", "))
+			}
+			assertions, err := LoadAssertions(expectPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			output, err := handler(t, eventPath)
+			if err != nil {
+				t.Fatalf("failed to handle test event %s: %v", eventPath, err)
+			}
+			results, err := EvaluateE(output, assertions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reporter.Record(eventPath, results...)
+			LogResults(t, results)
+			if err := ResultsError(results); err != nil {
+				t.Errorf("failed assertions from %s: %v", expectPath, err)
+			}
+		})
+	}
+}
+
+// findTestEvents returns the sorted `*.json` test events in dir, excluding assertion files
+func findTestEvents(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var events []string
+	for _, m := range matches {
+		if !strings.HasSuffix(m, ".expect.json") {
+			events = append(events, m)
+		}
+	}
+	sort.Strings(events)
+	return events, nil
+}
+
+func findExpectFile(eventPath string) (string, bool) {
+	base := strings.TrimSuffix(eventPath, ".json")
+	for _, suffix := range expectSuffixes {
+		if _, err := os.Stat(base + suffix); err == nil {
+			return base + suffix, true
+		}
+	}
+	return "", false
+}
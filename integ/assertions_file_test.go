@@ -0,0 +1,69 @@
+package integ
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAssertions(t *testing.T) {
+	assertions, err := LoadAssertions("./fixtures/testevents/no-file-name.expect.yaml")
+	require.NoError(t, err)
+	require.Len(t, assertions, 7)
+	assert.Equal(t, "regexp(^/$)", assertions[0].Matcher.String())
+	assert.Equal(t, "absent()", assertions[1].Matcher.String())
+	assert.True(t, assertions[2].Exists)
+	assert.Equal(t, "and(type(number), between(200, 299), not(equals(204)))", assertions[3].Matcher.String())
+	assert.Equal(t, All, assertions[6].Quantifier)
+
+	assertions, err = LoadAssertions("./fixtures/testevents/file-name-and-extension.expect.json")
+	require.NoError(t, err)
+	require.Len(t, assertions, 2)
+	assert.Equal(t, `or(equals("GET"), equals("HEAD"))`, assertions[1].Matcher.String())
+}
+
+func TestLoadAssertions_Invalid(t *testing.T) {
+	_, err := LoadAssertions("./fixtures/invalid.expect.yaml")
+	assert.Error(t, err, "Expected error due to unknown matcher")
+
+	_, err = LoadAssertions("./fixtures/does-not-exist.expect.yaml")
+	assert.Error(t, err, "Expected error due to missing file")
+
+	for description, spec := range map[string]AssertionSpec{
+		"empty path":        {Match: MatcherSpec{"equals": 1}},
+		"multiple matchers": {Path: "a", Match: MatcherSpec{"equals": 1, "gt": 0}},
+		"gt":                {Path: "a", Match: MatcherSpec{"gt": "1"}},
+		"between":           {Path: "a", Match: MatcherSpec{"between": []any{1}}},
+		"and":               {Path: "a", Match: MatcherSpec{"and": "equals"}},
+		"not":               {Path: "a", Match: MatcherSpec{"not": []any{}}},
+		"exists and match":  {Path: "a", Exists: new(bool), Match: MatcherSpec{"equals": 1}},
+		"quantifier":        {Path: "a", Quantifier: "most", Match: MatcherSpec{"equals": 1}},
+	} {
+		t.Run(description, func(t *testing.T) {
+			_, err := spec.Assertion()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRunEventAssertions(t *testing.T) {
+	reportDir := t.TempDir()
+	t.Setenv(ReportDirEnvVar, reportDir)
+	var handled []string
+	RunEventAssertions(t, "./fixtures/testevents", func(t *testing.T, eventPath string) (any, error) {
+		handled = append(handled, filepath.Base(eventPath))
+		data, err := os.ReadFile(eventPath)
+		if err != nil {
+			return nil, err
+		}
+		// echo the event as output
+		var output map[string]any
+		err = json.Unmarshal(data, &output)
+		return output, err
+	})
+	assert.Equal(t, []string{"file-name-and-extension.json", "no-file-name.json"}, handled)
+}
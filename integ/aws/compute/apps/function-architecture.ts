@@ -0,0 +1,45 @@
+import * as path from "path";
+import { App, LocalBackend } from "cdktf";
+import { aws } from "../../../../src";
+
+// templated by util.RunMatrix, values stay in string literals to keep the app valid TypeScript
+const architecture = "{{ .Architecture }}";
+
+const environmentName = process.env.ENVIRONMENT_NAME ?? "test";
+const region = process.env.AWS_REGION ?? "us-east-1";
+const outdir = process.env.OUT_DIR ?? "cdktf.out";
+const stackName = process.env.STACK_NAME ?? "function-architecture";
+
+const app = new App({
+  outdir,
+});
+const stack = new aws.AwsSpec(app, stackName, {
+  gridUUID: "12345678-1234",
+  environmentName,
+  providerConfig: {
+    region,
+  },
+});
+// TODO: use E.T. e2e s3 backend?
+new LocalBackend(stack, {
+  path: `${stackName}.tfstate`,
+});
+
+// public echo endpoint built for the templated architecture
+const echoLambda = new aws.compute.NodejsFunction(stack, "Echo", {
+  path: path.join(__dirname, "handlers", "echo", "index.ts"),
+  architecture:
+    architecture === "arm64"
+      ? aws.compute.Architecture.ARM_64
+      : aws.compute.Architecture.X86_64,
+  environment: {
+    NAME: stackName,
+  },
+  registerOutputs: true,
+  outputName: "echo",
+});
+echoLambda.addFunctionUrl({
+  authType: aws.compute.FunctionUrlAuthType.NONE,
+});
+
+app.synth();
@@ -3,12 +3,9 @@ package test
 import (
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 	"testing"
 	"time"
 
-	"github.com/environment-toolkit/go-synth/executors"
 	"github.com/gruntwork-io/terratest/modules/aws"
 	loggers "github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/stretchr/testify/assert"
@@ -16,6 +13,7 @@ import (
 
 	"github.com/envtio/base/integ"
 	util "github.com/envtio/base/integ/aws"
+	"github.com/envtio/base/integ/plan"
 	http_helper "github.com/gruntwork-io/terratest/modules/http-helper"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 )
@@ -23,23 +21,41 @@ import (
 var (
 	terratestLogger                               = loggers.Default
 	invocationTypeEvent util.InvocationTypeOption = util.InvocationTypeEvent
+	// chainReady polls the log group until the event bridge rules of the deploy deliver the events of the chain
+	chainReady = integ.PollOptions{Timeout: 3 * time.Minute, Interval: 10 * time.Second}
 )
 
 // Test the simple-ipv4-vpc app
 func TestNodeJsFunctionUrl(t *testing.T) {
-	runComputeIntegrationTestWithRename(t, "nodejs-function-url", "us-east-1", testFunctionUrl)
+	runComputeIntegrationTest(t, "nodejs-function-url", testFunctionUrl, renameStep)
+}
+
+// Test the templated function app for each architecture
+func TestFunctionArchitecture(t *testing.T) {
+	t.Parallel()
+	util.RunMatrix(t, "function-architecture", []util.Variables{
+		{"Architecture": "arm64"},
+		{"Architecture": "x86_64"},
+	}, func(t *testing.T, mc util.MatrixCase) {
+		integ.Run(t, integ.Scenario{
+			App:          mc.TestApp,
+			AppDirs:      []string{"handlers"},
+			Validate:     testFunctionUrl,
+			StackName:    mc.StackName,
+			TfWorkingDir: mc.TfWorkingDir,
+			Variables:    mc.Variables,
+		})
+	})
 }
 
 // Test the destinations integrations
 func TestDestinations(t *testing.T) {
-	runComputeIntegrationTest(t, "destinations", "us-east-1", validateDestinations)
+	runComputeIntegrationTest(t, "destinations", validateDestinations)
 }
 
 // Test the lambda-chain integration
 func TestLambdaChain(t *testing.T) {
-	runComputeIntegrationTest(t, "lambda-chain", "us-east-1", func(t *testing.T, tfWorkingDir, awsRegion string) {
-		// sleep for event bridge rules to be ready
-		time.Sleep(10 * time.Second)
+	runComputeIntegrationTest(t, "lambda-chain", func(t *testing.T, tfWorkingDir, awsRegion string) {
 		validateLambdaChainSuccess(t, tfWorkingDir, awsRegion)
 		validateLambdaChainFailure(t, tfWorkingDir, awsRegion)
 	})
@@ -47,17 +63,17 @@ func TestLambdaChain(t *testing.T) {
 
 // Test the event-source-sqs integration
 func TestEventSourceSqs(t *testing.T) {
-	runComputeIntegrationTest(t, "event-source-sqs", "us-east-1", validateEventSourceSqs)
+	runComputeIntegrationTest(t, "event-source-sqs", validateEventSourceSqs)
 }
 
 // Test the event-source-sqs-filtered integration
 func TestEventSourceSqsFiltered(t *testing.T) {
-	runComputeIntegrationTest(t, "event-source-sqs-filtered", "us-east-1", validateEventSourceSqsFiltered)
+	runComputeIntegrationTest(t, "event-source-sqs-filtered", validateEventSourceSqsFiltered)
 }
 
 // Test the event-source-s3 integration
 func TestEventSourceS3(t *testing.T) {
-	runComputeIntegrationTest(t, "event-source-s3", "us-east-1", validateEventSourceS3)
+	runComputeIntegrationTest(t, "event-source-s3", validateEventSourceS3)
 }
 
 // Ensure Function URL works
@@ -96,8 +112,8 @@ func validateDestinations(t *testing.T, tfWorkingDir string, awsRegion string) {
 			ExpectedRegexp: strPtr("OK"),
 		},
 		{
-			Path:           "responseContext.statusCode",
-			ExpectedRegexp: strPtr("200"),
+			Path:    "responseContext.statusCode",
+			Matcher: integ.Equals(200),
 		},
 		{
 			Path:           "responsePayload",
@@ -114,11 +130,7 @@ func validateLambdaChainSuccess(t *testing.T, tfWorkingDir string, awsRegion str
 	thirdFunctionName := util.LoadOutputAttribute(t, terraformOptions, "third_function", "name")
 	thirdFunctionLogGroup := fmt.Sprintf("/aws/lambda/%s", thirdFunctionName)
 	// https://github.com/aws/aws-cdk/blob/v2.161.1/packages/%40aws-cdk-testing/framework-integ/test/aws-lambda-destinations/test/integ.lambda-chain.ts#L65
-	util.InvokeFunctionWithParams(t, awsRegion, firstFunctionName, &util.LambdaOptions{
-		InvocationType: &invocationTypeEvent,
-		Payload:        map[string]interface{}{"status": "success"},
-	})
-	messages := util.WaitForLogEvents(t, awsRegion, thirdFunctionLogGroup, 12, 5*time.Second)
+	messages := invokeAndWaitForLogs(t, awsRegion, firstFunctionName, thirdFunctionLogGroup, map[string]interface{}{"status": "success"})
 	for _, message := range messages {
 		// we log messages only, no messages fails the test
 		terratestLogger.Logf(t, "Success Test: Message: %s", message)
@@ -132,17 +144,32 @@ func validateLambdaChainFailure(t *testing.T, tfWorkingDir string, awsRegion str
 	firstFunctionName := util.LoadOutputAttribute(t, terraformOptions, "first_function", "name")
 	errorFunctionName := util.LoadOutputAttribute(t, terraformOptions, "error_function", "name")
 	errorLogGroup := fmt.Sprintf("/aws/lambda/%s", errorFunctionName)
-	util.InvokeFunctionWithParams(t, awsRegion, firstFunctionName, &util.LambdaOptions{
-		InvocationType: &invocationTypeEvent,
-		Payload:        map[string]interface{}{"status": "error"},
-	})
-	messages := util.WaitForLogEvents(t, awsRegion, errorLogGroup, 12, 5*time.Second)
+	messages := invokeAndWaitForLogs(t, awsRegion, firstFunctionName, errorLogGroup, map[string]interface{}{"status": "error"})
 	for _, message := range messages {
 		// we log messages only, no messages fails the test
 		terratestLogger.Logf(t, "Failure Test: Message: %s", message)
 	}
 }
 
+// invokeAndWaitForLogs invokes the function asynchronously once and polls the log group until it receives events,
+// the event bridge rules of the deploy may take a while to deliver them. Fails the test after chainReady.
+func invokeAndWaitForLogs(t *testing.T, awsRegion, functionName, logGroup string, payload map[string]interface{}) []string {
+	util.InvokeFunctionWithParams(t, awsRegion, functionName, &util.LambdaOptions{
+		InvocationType: &invocationTypeEvent,
+		Payload:        payload,
+	})
+	var messages []string
+	integ.AssertEventually(t, func() (any, error) {
+		var err error
+		messages, err = util.FilterLogEventsE(t, awsRegion, logGroup)
+		return messages, err
+	}, []integ.Assertion{{Path: "[0]", Exists: true}}, chainReady)
+	if len(messages) == 0 {
+		t.FailNow()
+	}
+	return messages
+}
+
 // Validate the Destionation integration test
 func validateEventSourceSqs(t *testing.T, tfWorkingDir string, awsRegion string) {
 	// Load the Terraform Options saved by the earlier deploy_terraform stage
@@ -169,12 +196,26 @@ func validateEventSourceSqsFiltered(t *testing.T, tfWorkingDir string, awsRegion
 	functionLogGroup := fmt.Sprintf("/aws/lambda/%s", functionName)
 
 	messageBody := `{"id": "test"}`
-	aws.SendMessageToQueue(t, awsRegion, queueUrl, "random message") // should not trigger function
+	filteredMessageBody := "random message"
+	aws.SendMessageToQueue(t, awsRegion, queueUrl, filteredMessageBody) // should not trigger function
 	aws.SendMessageToQueue(t, awsRegion, queueUrl, messageBody)
 	assertFunctionLogMessage(t, awsRegion, functionLogGroup, integ.Assertion{
 		Path:           "Records[0].body",
 		ExpectedRegexp: &messageBody,
 	})
+	// prove the filtered message is never processed
+	integ.AssertConsistently(t, func() (any, error) {
+		return functionLogEvents(t, awsRegion, functionLogGroup)
+	}, []integ.Assertion{
+		{
+			Path:       "[].Records[].body",
+			Quantifier: integ.None,
+			Matcher:    integ.Equals(filteredMessageBody),
+		},
+	}, integ.PollOptions{
+		Duration: 30 * time.Second,
+		Interval: 5 * time.Second,
+	})
 }
 
 // Validate the Destionation integration test
@@ -213,69 +254,54 @@ func assertFunctionLogMessage(t *testing.T, awsRegion string, functionLogGroup s
 	}
 }
 
-// run integration test
-func runComputeIntegrationTest(t *testing.T, testApp, awsRegion string, validate func(t *testing.T, tfWorkingDir string, awsRegion string)) {
-	t.Parallel()
-	tfWorkingDir := filepath.Join("tf", testApp)
-	envVars := executors.EnvMap(os.Environ())
-	envVars["AWS_REGION"] = awsRegion
-	envVars["ENVIRONMENT_NAME"] = "test"
-	envVars["STACK_NAME"] = testApp
-
-	defer test_structure.RunTestStage(t, "cleanup_terraform", func() {
-		util.UndeployUsingTerraform(t, tfWorkingDir)
-	})
-
-	test_structure.RunTestStage(t, "synth_app", func() {
-		util.SynthApp(t, testApp, tfWorkingDir, envVars, "handlers")
-	})
-	test_structure.RunTestStage(t, "deploy_terraform", func() {
-		util.DeployUsingTerraform(t, tfWorkingDir, map[string]string{
-			// TODO: Fix Dependency tree to avoid this error :(
-			".*The EventInvokeConfig for function .* could not be updated due to a concurrent update operation.*": "Failed due to concurrent update operation.",
-		})
-	})
-	test_structure.RunTestStage(t, "validate", func() {
-		validate(t, tfWorkingDir, awsRegion)
-	})
+// functionLogEvents returns all events logged by the function as structured log message
+func functionLogEvents(t *testing.T, awsRegion string, functionLogGroup string) ([]any, error) {
+	logEntries, err := util.FilterLogEventsE(t, awsRegion, functionLogGroup)
+	if err != nil {
+		return nil, err
+	}
+	events := []any{}
+	for _, entry := range logEntries {
+		var logMessage map[string]interface{}
+		if err := json.Unmarshal([]byte(entry), &logMessage); err != nil {
+			// ignore unstructured lambda system messages
+			continue
+		}
+		message, ok := logMessage["message"].(string)
+		if !ok {
+			continue
+		}
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(message), &event); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event data: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
 }
 
-// run integration test and validate renaming the environment works without replacing any resources
-func runComputeIntegrationTestWithRename(t *testing.T, testApp, awsRegion string, validate func(t *testing.T, tfWorkingDir string, awsRegion string)) {
-	t.Parallel()
-	tfWorkingDir := filepath.Join("tf", testApp)
-	envVars := executors.EnvMap(os.Environ())
-	envVars["AWS_REGION"] = awsRegion
-	envVars["ENVIRONMENT_NAME"] = "test"
-	envVars["STACK_NAME"] = testApp
-
-	defer test_structure.RunTestStage(t, "cleanup_terraform", func() {
-		util.UndeployUsingTerraform(t, tfWorkingDir)
-	})
-
-	test_structure.RunTestStage(t, "synth_app", func() {
-		util.SynthApp(t, testApp, tfWorkingDir, envVars, "handlers")
-	})
-	test_structure.RunTestStage(t, "deploy_terraform", func() {
-		util.DeployUsingTerraform(t, tfWorkingDir, map[string]string{
-			// TODO: Fix Dependency tree to avoid this error :(
-			".*The EventInvokeConfig for function .* could not be updated due to a concurrent update operation.*": "Failed due to concurrent update operation.",
-		})
-	})
-	test_structure.RunTestStage(t, "validate", func() {
-		validate(t, tfWorkingDir, awsRegion)
-	})
+// retryable errors of the compute apps
+var computeRetryableErrors = map[string]string{
+	// TODO: Fix Dependency tree to avoid this error :(
+	".*The EventInvokeConfig for function .* could not be updated due to a concurrent update operation.*": "Failed due to concurrent update operation.",
+}
 
-	// rename the environment name
-	envVars["ENVIRONMENT_NAME"] = "renamed"
-	test_structure.RunTestStage(t, "rename_app", func() {
-		util.SynthApp(t, testApp, tfWorkingDir, envVars, "handlers")
+// run integration test
+func runComputeIntegrationTest(t *testing.T, testApp string, validate integ.Validator, day2 ...integ.Day2Step) {
+	integ.Run(t, integ.Scenario{
+		App:             testApp,
+		AppDirs:         []string{"handlers"},
+		RetryableErrors: computeRetryableErrors,
+		Validate:        validate,
+		Day2:            day2,
 	})
+}
 
-	// confirm no changes in plan
-	test_structure.RunTestStage(t, "validate_rename", func() {
-		replanUsingTerraform(t, tfWorkingDir)
-	})
+// renameStep renames the environment and confirms no resources are replaced
+var renameStep = integ.Day2Step{
+	Name:   "rename",
+	Env:    map[string]string{"ENVIRONMENT_NAME": "renamed"},
+	Expect: []plan.Expectation{plan.ExpectNoReplace()},
 }
 
 func strPtr(s string) *string {
@@ -2,14 +2,13 @@ package test
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 	"testing"
 	"time"
 
-	"github.com/environment-toolkit/go-synth/executors"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
 	"github.com/envtio/base/integ"
 	util "github.com/envtio/base/integ/aws"
+	"github.com/envtio/base/integ/redact"
 	"github.com/stretchr/testify/require"
 
 	// loggers "github.com/gruntwork-io/terratest/modules/logger"
@@ -20,18 +19,17 @@ import (
 
 // Test the multi-zone-acm-pub-cert app
 func TestMultiZoneAcmPubCert(t *testing.T) {
-	envVars := executors.EnvMap(os.Environ())
-	envVars["DNS_DOMAIN_NAME1"] = "test2.e2e.envt.io"
-	envVars["DNS_ZONE_ID1"] = "Z094619391UOQUZ5PKD4"
-	envVars["DNS_DOMAIN_NAME2"] = "test1.e2e.envt.io"
-	envVars["DNS_ZONE_ID2"] = "Z09470921W73LC945033M"
-	runEdgeIntegrationTest(t, "multi-zone-acm-pub-cert", "us-east-1", envVars, validateMultiZoneAcmPubCert)
+	runEdgeIntegrationTest(t, "multi-zone-acm-pub-cert", map[string]string{
+		"DNS_DOMAIN_NAME1": "test2.e2e.envt.io",
+		"DNS_ZONE_ID1":     "Z094619391UOQUZ5PKD4",
+		"DNS_DOMAIN_NAME2": "test1.e2e.envt.io",
+		"DNS_ZONE_ID2":     "Z09470921W73LC945033M",
+	}, validateMultiZoneAcmPubCert)
 }
 
 // Test the url-rewrite-spa app
 func TestUrlRewriteSpa(t *testing.T) {
-	envVars := executors.EnvMap(os.Environ())
-	runEdgeIntegrationTest(t, "url-rewrite-spa", "us-east-1", envVars, validateURLRewriteFunction)
+	runEdgeIntegrationTest(t, "url-rewrite-spa", nil, validateURLRewriteFunction)
 }
 
 // Secret to sign JWT Tokens for tests
@@ -39,9 +37,11 @@ const jwtTestSecret = "terratest-test-secret"
 
 // Test the kvs-jwt-verify app
 func TestKvsJwtVerify(t *testing.T) {
-	envVars := executors.EnvMap(os.Environ())
-	envVars["SECRET_KEY"] = jwtTestSecret
-	runEdgeIntegrationTest(t, "kvs-jwt-verify", "us-east-1", envVars, validateJwtVerifyFunction)
+	// the secret is not a sensitive output, mask it in synth and terraform logs
+	redact.Register(t, jwtTestSecret)
+	runEdgeIntegrationTest(t, "kvs-jwt-verify", map[string]string{
+		"SECRET_KEY": jwtTestSecret,
+	}, validateJwtVerifyFunction)
 }
 
 func validateMultiZoneAcmPubCert(t *testing.T, workingDir string, awsRegion string) {
@@ -51,47 +51,26 @@ func validateMultiZoneAcmPubCert(t *testing.T, workingDir string, awsRegion stri
 	util.WaitForCertificateIssued(t, certificateArn, awsRegion, 10, 10*time.Second)
 }
 
-// validateURLRewriteFunction with testevents
+// validateURLRewriteFunction with testevents and their `*.expect.yaml` assertions
 func validateURLRewriteFunction(t *testing.T, workingDir string, _awsRegion string) {
 	// Load the Terraform Options saved by the earlier deploy_terraform stage
 	terraformOptions := test_structure.LoadTerraformOptions(t, workingDir)
 	functionName := util.LoadOutputAttribute(t, terraformOptions, "url_rewrite_function", "name")
-	functionStage := "LIVE"
-	for name, testEventPath := range map[string]string{
-		"file-name-and-extension": "testevents/url-rewrite-spa/file-name-and-extension.json",
-		"file-name-no-extension":  "testevents/url-rewrite-spa/file-name-no-extension.json",
-		"no-file-name":            "testevents/url-rewrite-spa/no-file-name.json",
-	} {
-		t.Run(name, func(st *testing.T) {
-			st.Parallel()
-			testEvent, err := util.ReadCloudFrontEvent(testEventPath)
-			require.NoError(st, err)
-			require.NotNil(st, testEvent)
-			util.TestCloudFrontFunctionWithCustomValidation(st, functionName, functionStage, *testEvent,
-				func(r *util.CloudFrontTestFunctionResult) error {
-					if r.Output == nil {
-						return fmt.Errorf("got nil Output response")
-					}
-					// terratestLogger.Logf(t, fmt.Sprintf("Output: %v", r.Output))
-					switch testEvent.Request.URI {
-					case "/":
-						return integ.AssertE(r.Output, []integ.Assertion{
-							{
-								Path:           "request.uri",
-								ExpectedRegexp: strPtr("^/index.html$"),
-							}})
-					case "/blog", "/blog/index.html":
-						return integ.AssertE(r.Output, []integ.Assertion{
-							{
-								Path:           "request.uri",
-								ExpectedRegexp: strPtr("^/blog/index.html$"),
-							}})
-					default:
-						return fmt.Errorf("unexpected input testEvent URI: %s", testEvent.Request.URI)
-					}
-				})
-		})
-	}
+	integ.RunEventAssertions(t, "testevents/url-rewrite-spa", func(st *testing.T, testEventPath string) (any, error) {
+		st.Parallel()
+		testEvent, err := util.ReadCloudFrontEvent(testEventPath)
+		if err != nil {
+			return nil, err
+		}
+		r, err := util.TestCloudFrontFunctionE(st, functionName, types.FunctionStageLive, *testEvent)
+		if err != nil {
+			return nil, err
+		}
+		if r.Output == nil {
+			return nil, fmt.Errorf("got nil Output response")
+		}
+		return r.Output, nil
+	})
 }
 
 // validateJwtVerifyFunction with testevents
@@ -128,11 +107,10 @@ func validateJwtVerifyFunction(t *testing.T, workingDir string, _awsRegion strin
 						return nil
 					}
 
-					expectedStatusStr := fmt.Sprintf("%d", int(tc.expectedStatus))
 					return integ.AssertE(r.Output, []integ.Assertion{
 						{
-							Path:           "response.statusCode",
-							ExpectedRegexp: &expectedStatusStr,
+							Path:    "response.statusCode",
+							Matcher: integ.Equals(tc.expectedStatus),
 						},
 					})
 				})
@@ -159,29 +137,12 @@ func generateExpiredJWT(t *testing.T) string {
 	return jwt
 }
 
-func strPtr(s string) *string {
-	return &s
-}
-
 // run integration test
-func runEdgeIntegrationTest(t *testing.T, testApp, awsRegion string, envVars map[string]string, validate func(t *testing.T, tfWorkingDir string, awsRegion string)) {
-	t.Parallel()
-	tfWorkingDir := filepath.Join("tf", testApp)
-	envVars["AWS_REGION"] = awsRegion
-	envVars["ENVIRONMENT_NAME"] = "test"
-	envVars["STACK_NAME"] = testApp
-
-	defer test_structure.RunTestStage(t, "cleanup_terraform", func() {
-		util.UndeployUsingTerraform(t, tfWorkingDir)
-	})
-
-	test_structure.RunTestStage(t, "synth_app", func() {
-		util.SynthApp(t, testApp, tfWorkingDir, envVars, "handlers")
-	})
-	test_structure.RunTestStage(t, "deploy_terraform", func() {
-		util.DeployUsingTerraform(t, tfWorkingDir, nil)
-	})
-	test_structure.RunTestStage(t, "validate", func() {
-		validate(t, tfWorkingDir, awsRegion)
+func runEdgeIntegrationTest(t *testing.T, testApp string, envVars map[string]string, validate integ.Validator) {
+	integ.Run(t, integ.Scenario{
+		App:      testApp,
+		Env:      envVars,
+		AppDirs:  []string{"handlers"},
+		Validate: validate,
 	})
 }
@@ -0,0 +1,34 @@
+//go:build snapshots
+
+package test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/environment-toolkit/go-synth/executors"
+	util "github.com/envtio/base/integ/aws"
+)
+
+// The golden files of the edge apps are not generated yet, the snapshots build tag keeps TestSynthSnapshots
+// out of `go test ./...` until they are committed, see `make update-synth-snapshots`
+
+// Test the synth output of the edge apps against golden snapshots, without AWS credentials
+func TestSynthSnapshots(t *testing.T) {
+	for testApp, appEnv := range map[string]map[string]string{
+		"url-rewrite-spa": {},
+		"kvs-jwt-verify":  {"SECRET_KEY": jwtTestSecret},
+	} {
+		t.Run(testApp, func(t *testing.T) {
+			t.Parallel()
+			envVars := executors.EnvMap(os.Environ())
+			for k, v := range appEnv {
+				envVars[k] = v
+			}
+			envVars["AWS_REGION"] = "us-east-1"
+			envVars["ENVIRONMENT_NAME"] = "test"
+			envVars["STACK_NAME"] = testApp
+			util.SynthSnapshot(t, testApp, envVars, "handlers")
+		})
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
-	"github.com/environment-toolkit/go-synth/executors"
+	"github.com/envtio/base/integ"
 	util "github.com/envtio/base/integ/aws"
 	"github.com/gruntwork-io/terratest/modules/aws"
 
@@ -18,7 +18,7 @@ import (
 // Run the role integration test
 func TestRole(t *testing.T) {
 	testApp := "role"
-	runIamIntegrationTest(t, testApp, "us-east-1",
+	runIamIntegrationTest(t, testApp,
 		func(t *testing.T, tfWorkingDir string, awsRegion string) {
 			testCases := []struct {
 				outputs      string
@@ -48,7 +48,7 @@ func TestRole(t *testing.T) {
 // Run the composite-principal integration test
 func TestCompositePrincipal(t *testing.T) {
 	testApp := "composite-principal"
-	runIamIntegrationTest(t, testApp, "us-east-1",
+	runIamIntegrationTest(t, testApp,
 		func(t *testing.T, tfWorkingDir string, awsRegion string) {
 			snapshotPath := filepath.Join("snapshots", testApp)
 			validateRole(t, awsRegion, "RoleWithCompositePrincipalOutputs", tfWorkingDir, snapshotPath,
@@ -65,7 +65,7 @@ func TestCompositePrincipal(t *testing.T) {
 // Run the condition-with-ref integration test
 func TestConditionWithRef(t *testing.T) {
 	testApp := "condition-with-ref"
-	runIamIntegrationTest(t, testApp, "us-east-1",
+	runIamIntegrationTest(t, testApp,
 		func(t *testing.T, tfWorkingDir string, awsRegion string) {
 			snapshotPath := filepath.Join("snapshots", testApp)
 			validateRole(t, awsRegion, "MyRoleOutputs", tfWorkingDir, snapshotPath,
@@ -82,7 +82,7 @@ func TestConditionWithRef(t *testing.T) {
 // Run the managed-policy integration test
 func TestManagedPolicy(t *testing.T) {
 	testApp := "managed-policy"
-	runIamIntegrationTest(t, testApp, "us-east-1",
+	runIamIntegrationTest(t, testApp,
 		func(t *testing.T, tfWorkingDir string, awsRegion string) {
 			snapshotPath := filepath.Join("snapshots", testApp)
 			validateRole(t, awsRegion, "RoleOutputs", tfWorkingDir, snapshotPath,
@@ -155,25 +155,9 @@ func validateManagedPolicy(t *testing.T, awsRegion string, managedRoleKey string
 }
 
 // run integration test
-func runIamIntegrationTest(t *testing.T, testApp, awsRegion string, validate func(t *testing.T, tfWorkingDir string, awsRegion string)) {
-	t.Parallel()
-	tfWorkingDir := filepath.Join("tf", testApp)
-	envVars := executors.EnvMap(os.Environ())
-	envVars["AWS_REGION"] = awsRegion
-	envVars["ENVIRONMENT_NAME"] = "test"
-	envVars["STACK_NAME"] = testApp
-
-	defer test_structure.RunTestStage(t, "cleanup_terraform", func() {
-		util.UndeployUsingTerraform(t, tfWorkingDir)
-	})
-
-	test_structure.RunTestStage(t, "synth_app", func() {
-		util.SynthApp(t, testApp, tfWorkingDir, envVars)
-	})
-	test_structure.RunTestStage(t, "deploy_terraform", func() {
-		util.DeployUsingTerraform(t, tfWorkingDir, nil)
-	})
-	test_structure.RunTestStage(t, "validate", func() {
-		validate(t, tfWorkingDir, awsRegion)
+func runIamIntegrationTest(t *testing.T, testApp string, validate integ.Validator) {
+	integ.Run(t, integ.Scenario{
+		App:      testApp,
+		Validate: validate,
 	})
 }
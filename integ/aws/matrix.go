@@ -0,0 +1,91 @@
+package aws
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+// MatrixCase is a parameter set of RunMatrix.
+type MatrixCase struct {
+	Name         string    // Subtest name, the sorted `key=value` pairs of Variables
+	TestApp      string    // Name of the test app
+	StackName    string    // Stack name unique to the parameter set, `<testApp>-<values>`
+	TfWorkingDir string    // Terraform working dir unique to the parameter set, `tf/<testApp>/<stack name>`
+	Variables    Variables // Variables to template the test app with
+}
+
+// SynthOptions returns the options to synth the templated test app of the parameter set,
+// pass StackName as the STACK_NAME env var of the app.
+func (mc MatrixCase) SynthOptions(env map[string]string, additionalAppDirs ...string) SynthOptions {
+	return SynthOptions{
+		TestApp:           mc.TestApp,
+		TfWorkingDir:      mc.TfWorkingDir,
+		Env:               env,
+		AdditionalAppDirs: additionalAppDirs,
+		StackName:         mc.StackName,
+		Variables:         mc.Variables,
+	}
+}
+
+// RunMatrix runs a subtest per parameter set of a templated test app, each with its own stack name and working dir.
+//
+//	util.RunMatrix(t, "function-architecture", []util.Variables{
+//		{"Architecture": "arm64"},
+//		{"Architecture": "x86_64"},
+//	}, func(t *testing.T, mc util.MatrixCase) { ... })
+func RunMatrix(t *testing.T, testApp string, matrix []Variables, run func(t *testing.T, mc MatrixCase)) {
+	seen := map[string]bool{}
+	for _, vars := range matrix {
+		mc := NewMatrixCase(testApp, vars)
+		if seen[mc.Name] {
+			t.Fatalf("duplicate parameter set '%s' in matrix of %s", mc.Name, testApp)
+		}
+		seen[mc.Name] = true
+		t.Run(mc.Name, func(t *testing.T) {
+			run(t, mc)
+		})
+	}
+}
+
+var stackNameRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+// NewMatrixCase returns the MatrixCase of a parameter set.
+func NewMatrixCase(testApp string, vars Variables) MatrixCase {
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	values := []string{testApp}
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", k, vars[k]))
+		values = append(values, fmt.Sprintf("%v", vars[k]))
+	}
+	stackName := strings.Trim(stackNameRegexp.ReplaceAllString(strings.ToLower(strings.Join(values, "-")), "-"), "-")
+	return MatrixCase{
+		Name:         strings.Join(pairs, ","),
+		TestApp:      testApp,
+		StackName:    stackName,
+		TfWorkingDir: filepath.Join("tf", testApp, stackName),
+		Variables:    vars,
+	}
+}
+
+// renderApp applies the variables to the test app source, referencing missing variables is an error
+func renderApp(name, contents string, vars Variables) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(contents)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, vars); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,47 @@
+package aws
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMatrixCase(t *testing.T) {
+	mc := NewMatrixCase("queue", Variables{"Fifo": true, "Architecture": "x86_64"})
+	assert.Equal(t, "Architecture=x86_64,Fifo=true", mc.Name)
+	assert.Equal(t, "queue-x86-64-true", mc.StackName)
+	assert.Equal(t, filepath.Join("tf", "queue", "queue-x86-64-true"), mc.TfWorkingDir)
+
+	opts := mc.SynthOptions(map[string]string{"STACK_NAME": mc.StackName}, "handlers")
+	assert.Equal(t, "queue", opts.TestApp)
+	assert.Equal(t, mc.StackName, opts.StackName)
+	assert.Equal(t, mc.TfWorkingDir, opts.TfWorkingDir)
+	assert.Equal(t, []string{"handlers"}, opts.AdditionalAppDirs)
+	assert.Equal(t, mc.Variables, opts.Variables)
+}
+
+func TestRunMatrix(t *testing.T) {
+	var cases []MatrixCase
+	RunMatrix(t, "function", []Variables{
+		{"Architecture": "arm64"},
+		{"Architecture": "x86_64"},
+	}, func(t *testing.T, mc MatrixCase) {
+		assert.Equal(t, "TestRunMatrix/"+mc.Name, t.Name())
+		cases = append(cases, mc)
+	})
+	require.Len(t, cases, 2)
+	assert.Equal(t, "function-arm64", cases[0].StackName)
+	assert.Equal(t, "function-x86-64", cases[1].StackName)
+}
+
+func TestRenderApp(t *testing.T) {
+	app := `const architecture = "{{ .Architecture }}";`
+	rendered, err := renderApp("function.ts", app, Variables{"Architecture": "arm64"})
+	require.NoError(t, err)
+	assert.Equal(t, `const architecture = "arm64";`, rendered)
+
+	_, err = renderApp("function.ts", app, Variables{"Fifo": true})
+	assert.ErrorContains(t, err, `map has no entry for key "Architecture"`)
+}
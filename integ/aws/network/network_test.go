@@ -1,11 +1,8 @@
 package test
 
 import (
-	"os"
-	"path/filepath"
 	"testing"
 
-	"github.com/environment-toolkit/go-synth/executors"
 	"github.com/envtio/base/integ"
 	util "github.com/envtio/base/integ/aws"
 	loggers "github.com/gruntwork-io/terratest/modules/logger"
@@ -18,31 +15,12 @@ var terratestLogger = loggers.Default
 
 // Test the simple-ipv4-vpc app
 func TestSimpleIPv4Vpc(t *testing.T) {
-	t.Parallel()
-	testApp := "simple-ipv4-vpc"
-	awsRegion := "us-east-1"
-
-	envVars := executors.EnvMap(os.Environ())
-	envVars["AWS_REGION"] = awsRegion
-	envVars["ENVIRONMENT_NAME"] = "test"
-	envVars["STACK_NAME"] = testApp
-
-	tfWorkingDir := filepath.Join("tf", testApp)
-	defer test_structure.RunTestStage(t, "cleanup_terraform", func() {
-		util.UndeployUsingTerraform(t, tfWorkingDir)
-	})
-
-	test_structure.RunTestStage(t, "synth_app", func() {
+	integ.Run(t, integ.Scenario{
+		App: "simple-ipv4-vpc",
 		// synth app with handlers for connectivity testing
-		util.SynthApp(t, testApp, tfWorkingDir, envVars, "handlers")
-	})
-	test_structure.RunTestStage(t, "deploy_terraform", func() {
-		util.DeployUsingTerraform(t, tfWorkingDir, nil)
-	})
-
-	// Validate the network connectivity
-	test_structure.RunTestStage(t, "validate", func() {
-		validateWithLambdaInvocations(t, tfWorkingDir, awsRegion)
+		AppDirs: []string{"handlers"},
+		// Validate the network connectivity
+		Validate: validateWithLambdaInvocations,
 	})
 }
 
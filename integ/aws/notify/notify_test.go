@@ -1,13 +1,13 @@
 package test
 
 import (
-	"os"
 	"path/filepath"
 	"strconv"
 	"testing"
 
-	"github.com/environment-toolkit/go-synth/executors"
+	"github.com/envtio/base/integ"
 	util "github.com/envtio/base/integ/aws"
+	"github.com/envtio/base/integ/plan"
 	"github.com/gruntwork-io/terratest/modules/aws"
 	loggers "github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/stretchr/testify/assert"
@@ -19,28 +19,44 @@ var terratestLogger = loggers.Default
 
 // Test the fifo-queue app
 func TestFifoQueue(t *testing.T) {
-	envVars := executors.EnvMap(os.Environ())
-	// Confirm the FIFO queue is working as expected
-	runNotifyIntegrationTest(t, "fifo-queue", "us-east-1", envVars, validateFifoQueue)
+	// Confirm the FIFO queue is working as expected and can be adopted by import
+	integ.Run(t, integ.Scenario{
+		App:             "fifo-queue",
+		Validate:        validateFifoQueue,
+		ImportRoundTrip: &plan.ImportOptions{Allow: []string{"aws_sqs_queue*"}},
+	})
 }
 
 // Test the dlq-queue app
 func TestDlqQueue(t *testing.T) {
 	testApp := "dlq-queue"
-	awsRegion := "us-east-1"
 	// set low maxReceiveCount to trigger DLQ
 	maxReceiveCount := 2
 	visibilityTimeoutSeconds := 5
 
-	envVars := executors.EnvMap(os.Environ())
-	envVars["MAX_RECEIVE_COUNT"] = strconv.Itoa(maxReceiveCount)
-	envVars["VISIBILITY_TIMEOUT_SECONDS"] = strconv.Itoa(visibilityTimeoutSeconds)
-
 	// save maxReceiveCount for future stages
 	tfWorkingDir := filepath.Join("tf", testApp)
 	test_structure.SaveInt(t, tfWorkingDir, "max_receive_count", maxReceiveCount)
 	// Confirm the DLQ queue is working as expected
-	runNotifyIntegrationTest(t, testApp, awsRegion, envVars, validateDlqQueue)
+	integ.Run(t, integ.Scenario{
+		App: testApp,
+		Env: map[string]string{
+			"MAX_RECEIVE_COUNT":          strconv.Itoa(maxReceiveCount),
+			"VISIBILITY_TIMEOUT_SECONDS": strconv.Itoa(visibilityTimeoutSeconds),
+		},
+		Validate: validateDlqQueue,
+		Day2: []integ.Day2Step{{
+			// raising maxReceiveCount only updates the redrive policy of the source queue
+			Name:   "raise_max_receive_count",
+			Env:    map[string]string{"MAX_RECEIVE_COUNT": strconv.Itoa(maxReceiveCount + 1)},
+			Expect: []plan.Expectation{plan.ExpectOnlyUpdates("aws_sqs_queue.*")},
+			Apply:  true,
+			Validate: func(t *testing.T, tfWorkingDir string, awsRegion string) {
+				test_structure.SaveInt(t, tfWorkingDir, "max_receive_count", maxReceiveCount+1)
+				validateDlqQueue(t, tfWorkingDir, awsRegion)
+			},
+		}},
+	})
 }
 
 func validateFifoQueue(t *testing.T, workingDir string, awsRegion string) {
@@ -97,26 +113,3 @@ func validateDlqQueue(t *testing.T, workingDir string, awsRegion string) {
 	// Delete the message from the DLQ
 	aws.DeleteMessageFromQueue(t, awsRegion, dlqUrl, dlqMsgResponse.ReceiptHandle)
 }
-
-// run integration test
-func runNotifyIntegrationTest(t *testing.T, testApp, awsRegion string, envVars map[string]string, validate func(t *testing.T, tfWorkingDir string, awsRegion string)) {
-	t.Parallel()
-	tfWorkingDir := filepath.Join("tf", testApp)
-	envVars["AWS_REGION"] = awsRegion
-	envVars["ENVIRONMENT_NAME"] = "test"
-	envVars["STACK_NAME"] = testApp
-
-	defer test_structure.RunTestStage(t, "cleanup_terraform", func() {
-		util.UndeployUsingTerraform(t, tfWorkingDir)
-	})
-
-	test_structure.RunTestStage(t, "synth_app", func() {
-		util.SynthApp(t, testApp, tfWorkingDir, envVars)
-	})
-	test_structure.RunTestStage(t, "deploy_terraform", func() {
-		util.DeployUsingTerraform(t, tfWorkingDir, nil)
-	})
-	test_structure.RunTestStage(t, "validate", func() {
-		validate(t, tfWorkingDir, awsRegion)
-	})
-}
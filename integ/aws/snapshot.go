@@ -0,0 +1,158 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	// SnapshotDir is the directory of golden snapshot files relative to the integration namespace
+	SnapshotDir = "testdata/snapshots"
+	// UpdateSnapshotsEnvVar set to `true` creates or updates the golden snapshot files
+	UpdateSnapshotsEnvVar = "UPDATE_SNAPSHOTS"
+
+	hashPlaceholder    = "<hash>"
+	versionPlaceholder = "<version>"
+	pathPlaceholder    = "<path>"
+)
+
+var (
+	// hex digests of cdktf assets (sha256) and archive hashes (md5, sha1)
+	hashRegexp = regexp.MustCompile(`\b(?:[0-9a-fA-F]{64}|[0-9a-fA-F]{40}|[0-9a-fA-F]{32})\b`)
+	// base64 encoded sha256 digests, i.e. `source_code_hash` of Lambda functions
+	base64HashRegexp = regexp.MustCompile(`^[A-Za-z0-9+/]{43}=$`)
+)
+
+// SynthSnapshot synths the test app without deploying and compares the normalized cdk.tf.json
+// to the golden file `testdata/snapshots/<testApp>.tf.json`.
+//
+// Run with UPDATE_SNAPSHOTS=true to create or update the golden file, the test fails if the golden file is missing.
+func SynthSnapshot(t *testing.T, testApp string, env map[string]string, additionalAppDirs ...string) {
+	goldenFile := filepath.Join(SnapshotDir, testApp+".tf.json")
+	update := os.Getenv(UpdateSnapshotsEnvVar) == "true"
+	expected, err := os.ReadFile(goldenFile)
+	if err != nil && !update {
+		t.Fatalf("Failed to read synth snapshot %s, run with %s=true to create it: %v", goldenFile, UpdateSnapshotsEnvVar, err)
+	}
+
+	tfWorkingDir := t.TempDir()
+	SynthApp(t, testApp, tfWorkingDir, env, additionalAppDirs...)
+	synthOutput, err := os.ReadFile(filepath.Join(tfWorkingDir, "cdk.tf.json"))
+	if err != nil {
+		t.Fatalf("Failed to read synth output of %s: %v", testApp, err)
+	}
+	actual, err := NormalizeSynthOutput(synthOutput, volatilePaths(tfWorkingDir)...)
+	if err != nil {
+		t.Fatalf("Failed to normalize synth output of %s: %v", testApp, err)
+	}
+
+	if update {
+		if err := os.MkdirAll(SnapshotDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(goldenFile, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+		terratestLogger.Logf(t, "Updated synth snapshot %s", goldenFile)
+		return
+	}
+	if diff := cmp.Diff(string(expected), string(actual)); diff != "" {
+		t.Errorf("Synth output of %s does not match snapshot %s, run with %s=true if the change is expected (-want +got):\n%s", testApp, goldenFile, UpdateSnapshotsEnvVar, diff)
+	}
+}
+
+// volatilePaths returns the absolute paths which differ between machines and test runs
+func volatilePaths(tfWorkingDir string) []string {
+	var paths []string
+	for _, p := range []string{tfWorkingDir, repoRoot, "."} {
+		if abs, err := filepath.Abs(p); err == nil {
+			paths = append(paths, abs)
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, home)
+	}
+	return append(paths, os.TempDir())
+}
+
+// NormalizeSynthOutput replaces volatile values of a synthesized cdk.tf.json with placeholders
+// and returns indented JSON with sorted keys:
+//
+//   - asset and source code hashes with `<hash>`
+//   - provider and cdktf versions with `<version>`
+//   - the given absolute paths with `<path>`
+func NormalizeSynthOutput(synthOutput []byte, volatilePaths ...string) ([]byte, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(synthOutput, &doc); err != nil {
+		return nil, fmt.Errorf("invalid synth output: %v", err)
+	}
+	// longest paths first, so nested paths are fully replaced
+	paths := append([]string{}, volatilePaths...)
+	sort.Slice(paths, func(i, j int) bool { return len(paths[i]) > len(paths[j]) })
+
+	// cdktf version of the stack metadata
+	if meta, ok := doc["//"].(map[string]any); ok {
+		if metadata, ok := meta["metadata"].(map[string]any); ok {
+			if _, ok := metadata["version"]; ok {
+				metadata["version"] = versionPlaceholder
+			}
+		}
+	}
+	// provider version constraints
+	if tf, ok := doc["terraform"].(map[string]any); ok {
+		if providers, ok := tf["required_providers"].(map[string]any); ok {
+			for _, p := range providers {
+				if provider, ok := p.(map[string]any); ok {
+					if _, ok := provider["version"]; ok {
+						provider["version"] = versionPlaceholder
+					}
+				}
+			}
+		}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false) // keep placeholders readable
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(normalizeVolatileStrings(doc, paths)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// normalizeVolatileStrings replaces hashes and volatile paths in all strings of a JSON value
+func normalizeVolatileStrings(value any, volatilePaths []string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, elem := range v {
+			v[k] = normalizeVolatileStrings(elem, volatilePaths)
+		}
+		return v
+	case []any:
+		for i, elem := range v {
+			v[i] = normalizeVolatileStrings(elem, volatilePaths)
+		}
+		return v
+	case string:
+		for _, p := range volatilePaths {
+			if p != "" && p != "/" {
+				v = strings.ReplaceAll(v, p, pathPlaceholder)
+			}
+		}
+		if base64HashRegexp.MatchString(v) {
+			return hashPlaceholder
+		}
+		return hashRegexp.ReplaceAllString(v, hashPlaceholder)
+	default:
+		return value
+	}
+}
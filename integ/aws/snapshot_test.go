@@ -0,0 +1,69 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeSynthOutput(t *testing.T) {
+	synthOutput := []byte(`{
+  "//": {
+    "metadata": {
+      "backend": "local",
+      "stackName": "url-rewrite-spa",
+      "version": "0.20.9"
+    }
+  },
+  "terraform": {
+    "required_providers": {
+      "aws": {
+        "source": "aws",
+        "version": "5.70.0"
+      }
+    }
+  },
+  "resource": {
+    "aws_lambda_function": {
+      "Function": {
+        "filename": "assets/FunctionCode/0F3A6C2D9E8B7A615243F5E6D7C8B9A00F3A6C2D9E8B7A615243F5E6D7C8B9A0/archive.zip",
+        "source_code_hash": "n4bQgYhMfWWaL+qgxVrQFaO/TxsrC4Is0V1sFbDwCgg=",
+        "description": "built in /home/runner/work/base/base/integ/aws/compute"
+      }
+    }
+  }
+}`)
+	normalized, err := NormalizeSynthOutput(synthOutput, "/home/runner", "/home/runner/work/base/base")
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "//": {
+    "metadata": {
+      "backend": "local",
+      "stackName": "url-rewrite-spa",
+      "version": "<version>"
+    }
+  },
+  "resource": {
+    "aws_lambda_function": {
+      "Function": {
+        "description": "built in <path>/integ/aws/compute",
+        "filename": "assets/FunctionCode/<hash>/archive.zip",
+        "source_code_hash": "<hash>"
+      }
+    }
+  },
+  "terraform": {
+    "required_providers": {
+      "aws": {
+        "source": "aws",
+        "version": "<version>"
+      }
+    }
+  }
+}
+`, string(normalized))
+
+	_, err = NormalizeSynthOutput([]byte(`not json`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,179 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/require"
+)
+
+// ManifestFileName is the manifest of all stacks written by cdktf next to the `stacks` dir
+const ManifestFileName = "manifest.json"
+
+// ref https://github.com/hashicorp/terraform-cdk/blob/v0.20.7/packages/cdktf/lib/manifest.ts
+
+// Manifest is the `cdktf.out/manifest.json` of a synthesized app.
+type Manifest struct {
+	Version string                   `json:"version"`
+	Stacks  map[string]ManifestStack `json:"stacks"`
+}
+
+// ManifestStack is a stack of the Manifest.
+type ManifestStack struct {
+	Name                 string       `json:"name"`
+	ConstructPath        string       `json:"constructPath"`
+	SynthesizedStackPath string       `json:"synthesizedStackPath"` // cdk.tf.json relative to the manifest, i.e. `stacks/<name>/cdk.tf.json`
+	WorkingDirectory     string       `json:"workingDirectory"`     // Terraform working dir relative to the manifest, i.e. `stacks/<name>`
+	Annotations          []Annotation `json:"annotations"`
+	Dependencies         []string     `json:"dependencies"` // Names of the stacks this stack depends on
+}
+
+// Annotation is an info, warning or error message added to a construct during synth.
+type Annotation struct {
+	ConstructPath string   `json:"constructPath"`
+	Level         string   `json:"level"` // `@cdktf/info`, `@cdktf/warn` or `@cdktf/error`
+	Message       string   `json:"message"`
+	Stacktrace    []string `json:"stacktrace,omitempty"`
+}
+
+// Stacks are the stacks of an app synthesized by SynthStacks.
+type Stacks struct {
+	TfWorkingDir string    // Directory the app was synthesized to, containing the manifest
+	Manifest     *Manifest // Parsed manifest
+	Order        []string  // Stack names in deployment order, dependencies first
+}
+
+// SynthStacks synths all stacks of a test app to `<tfWorkingDir>/stacks/<stack name>` and returns them in deployment order.
+// This fails the test on any errors
+func SynthStacks(t *testing.T, opts SynthOptions) *Stacks {
+	opts.AllStacks = true
+	SynthAppWithOptions(t, opts)
+	return LoadStacks(t, opts.TfWorkingDir)
+}
+
+// LoadStacks loads the manifest written by SynthStacks. This fails the test on any errors
+func LoadStacks(t *testing.T, tfWorkingDir string) *Stacks {
+	stacks, err := LoadStacksE(tfWorkingDir)
+	require.NoError(t, err)
+	return stacks
+}
+
+// LoadStacksE loads the manifest written by SynthStacks.
+func LoadStacksE(tfWorkingDir string) (*Stacks, error) {
+	path := filepath.Join(tfWorkingDir, ManifestFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+	order, err := manifest.DeploymentOrder()
+	if err != nil {
+		return nil, err
+	}
+	return &Stacks{TfWorkingDir: tfWorkingDir, Manifest: &manifest, Order: order}, nil
+}
+
+// DeploymentOrder returns the stack names sorted so every stack follows its dependencies, stacks without dependencies between them are sorted by name.
+func (m *Manifest) DeploymentOrder() ([]string, error) {
+	inDegree := make(map[string]int, len(m.Stacks))
+	dependents := map[string][]string{}
+	for name, stack := range m.Stacks {
+		inDegree[name] += 0
+		for _, dep := range stack.Dependencies {
+			if _, ok := m.Stacks[dep]; !ok {
+				return nil, fmt.Errorf("stack '%s' depends on unknown stack '%s'", name, dep)
+			}
+			inDegree[name]++
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+	var ready, order []string
+	for name, degree := range inDegree {
+		if degree == 0 {
+			ready = append(ready, name)
+		}
+	}
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+		for _, dependent := range dependents[name] {
+			inDegree[dependent]--
+			if inDegree[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	if len(order) != len(m.Stacks) {
+		var cyclic []string
+		for name, degree := range inDegree {
+			if degree > 0 {
+				cyclic = append(cyclic, name)
+			}
+		}
+		sort.Strings(cyclic)
+		return nil, fmt.Errorf("dependency cycle between stacks %s", strings.Join(cyclic, ", "))
+	}
+	return order, nil
+}
+
+// WorkingDir returns the Terraform working dir of the stack.
+func (s *Stacks) WorkingDir(name string) string {
+	stack, ok := s.Manifest.Stacks[name]
+	if !ok || stack.WorkingDirectory == "" {
+		return filepath.Join(s.TfWorkingDir, "stacks", name)
+	}
+	return filepath.Join(s.TfWorkingDir, stack.WorkingDirectory)
+}
+
+// TerraformOptions returns the Terraform Options of the stack saved by DeployStacksUsingTerraform, for validators to look up stacks by name.
+func (s *Stacks) TerraformOptions(t *testing.T, name string) *terraform.Options {
+	if _, ok := s.Manifest.Stacks[name]; !ok {
+		t.Fatalf("stack '%s' not found in %s, stacks: %s", name, s.TfWorkingDir, strings.Join(s.Order, ", "))
+	}
+	return LoadTerraformOptions(t, s.WorkingDir(name))
+}
+
+// DeployStacksUsingTerraform deploys all stacks synthesized by SynthStacks to tfWorkingDir in dependency order.
+func DeployStacksUsingTerraform(t *testing.T, tfWorkingDir string, additionalRetryableErrors map[string]string) {
+	stacks := LoadStacks(t, tfWorkingDir)
+	for _, name := range stacks.Order {
+		terratestLogger.Logf(t, "Deploying stack %s", name)
+		DeployUsingTerraform(t, stacks.WorkingDir(name), additionalRetryableErrors)
+	}
+}
+
+// UndeployStacksUsingTerraform destroys all deployed stacks of tfWorkingDir in reverse dependency order.
+// Stacks which were never deployed are skipped, all stacks are destroyed even if destroying a stack fails.
+func UndeployStacksUsingTerraform(t *testing.T, tfWorkingDir string) {
+	stacks := LoadStacks(t, tfWorkingDir)
+	var combinedErr error
+	for i := len(stacks.Order) - 1; i >= 0; i-- {
+		name := stacks.Order[i]
+		workingDir := stacks.WorkingDir(name)
+		if !test_structure.IsTestDataPresent(t, test_structure.FormatTestDataPath(workingDir, "TerraformOptions.json")) {
+			terratestLogger.Logf(t, "Skipping destroy of stack %s, it was not deployed", name)
+			continue
+		}
+		terratestLogger.Logf(t, "Destroying stack %s", name)
+		terraformOptions := LoadTerraformOptions(t, workingDir)
+		if _, err := terraform.DestroyE(t, terraformOptions); err != nil {
+			combinedErr = multierror.Append(combinedErr, fmt.Errorf("failed to destroy stack '%s': %v", name, err))
+		}
+	}
+	if combinedErr != nil {
+		t.Error(combinedErr)
+	}
+}
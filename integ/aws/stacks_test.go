@@ -0,0 +1,111 @@
+package aws
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifest_DeploymentOrder(t *testing.T) {
+	testCases := []struct {
+		name     string
+		stacks   map[string][]string // dependencies by stack name
+		expected []string
+		err      string
+	}{
+		{
+			name:     "single stack",
+			stacks:   map[string][]string{"app": nil},
+			expected: []string{"app"},
+		},
+		{
+			name: "certificate and app",
+			stacks: map[string][]string{
+				"app":         {"certificate"},
+				"certificate": nil,
+			},
+			expected: []string{"certificate", "app"},
+		},
+		{
+			name: "diamond",
+			stacks: map[string][]string{
+				"app":      {"network", "dns"},
+				"dns":      {"base"},
+				"network":  {"base"},
+				"base":     nil,
+				"monitors": {"app"},
+			},
+			expected: []string{"base", "dns", "network", "app", "monitors"},
+		},
+		{
+			name:   "unknown dependency",
+			stacks: map[string][]string{"app": {"missing"}},
+			err:    "stack 'app' depends on unknown stack 'missing'",
+		},
+		{
+			name: "cycle",
+			stacks: map[string][]string{
+				"a":    {"b"},
+				"b":    {"a"},
+				"base": nil,
+			},
+			err: "dependency cycle between stacks a, b",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Manifest{Stacks: map[string]ManifestStack{}}
+			for name, deps := range tc.stacks {
+				m.Stacks[name] = ManifestStack{Name: name, Dependencies: deps}
+			}
+			order, err := m.DeploymentOrder()
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, order)
+		})
+	}
+}
+
+func TestLoadStacks(t *testing.T) {
+	tfWorkingDir := t.TempDir()
+	writeTestFile(t, filepath.Join(tfWorkingDir, ManifestFileName), `{
+  "version": "0.20.7",
+  "stacks": {
+    "app": {
+      "name": "app",
+      "constructPath": "app",
+      "synthesizedStackPath": "stacks/app/cdk.tf.json",
+      "workingDirectory": "stacks/app",
+      "annotations": [],
+      "dependencies": ["certificate"]
+    },
+    "certificate": {
+      "name": "certificate",
+      "constructPath": "certificate",
+      "synthesizedStackPath": "stacks/certificate/cdk.tf.json",
+      "workingDirectory": "stacks/certificate",
+      "annotations": [
+        {"constructPath": "certificate/Certificate", "level": "@cdktf/warn", "message": "validation may take a while"}
+      ],
+      "dependencies": []
+    }
+  }
+}`)
+	stacks := LoadStacks(t, tfWorkingDir)
+	assert.Equal(t, "0.20.7", stacks.Manifest.Version)
+	assert.Equal(t, []string{"certificate", "app"}, stacks.Order)
+	assert.Equal(t, filepath.Join(tfWorkingDir, "stacks", "app"), stacks.WorkingDir("app"))
+	assert.Equal(t, []Annotation{{
+		ConstructPath: "certificate/Certificate",
+		Level:         "@cdktf/warn",
+		Message:       "validation may take a while",
+	}}, stacks.Manifest.Stacks["certificate"].Annotations)
+
+	_, err := LoadStacksE(t.TempDir())
+	assert.Error(t, err)
+}
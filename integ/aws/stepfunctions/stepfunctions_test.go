@@ -2,14 +2,14 @@ package test
 
 import (
 	"encoding/json"
-	"os"
+	"fmt"
 	"path/filepath"
 	"strconv"
 	"testing"
 	"time"
 
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sfn/types"
-	"github.com/environment-toolkit/go-synth/executors"
 	"github.com/envtio/base/integ"
 	util "github.com/envtio/base/integ/aws"
 	"github.com/gruntwork-io/terratest/modules/aws"
@@ -21,9 +21,15 @@ import (
 
 var terratestLogger = loggers.Default
 
+// executionStopped polls the status of an execution until it stops
+var executionStopped = integ.PollOptions{Timeout: 2 * time.Minute, Interval: 3 * time.Second}
+
+// maxExecutions bounds the executions started until the IAM policies of the deploy have propagated to the state machine role
+const maxExecutions = 3
+
 // Run the apps/call-aws-service.ts integration test
 func TestCallAwsService(t *testing.T) {
-	runStepfunctionsIntegrationTest(t, "call-aws-service", "us-east-1",
+	runStepfunctionsIntegrationTest(t, "call-aws-service",
 		func(t *testing.T, tfWorkingDir string, awsRegion string) {
 			message := "hello world!"
 			input := map[string]interface{}{
@@ -41,42 +47,42 @@ func TestCallAwsService(t *testing.T) {
 
 // Run the apps/call-aws-service-sfn.ts integration test
 func TestCallAwsServiceSfn(t *testing.T) {
-	runStepfunctionsIntegrationTest(t, "call-aws-service-sfn", "us-east-1", validateStateMachineSucceeds)
+	runStepfunctionsIntegrationTest(t, "call-aws-service-sfn", validateStateMachineSucceeds)
 }
 
 // Run the apps/call-aws-service-mwaa.ts integration test
 func TestCallAwsServiceMwaa(t *testing.T) {
-	runStepfunctionsIntegrationTest(t, "call-aws-service-mwaa", "us-east-1", validateStateMachineSucceeds)
+	runStepfunctionsIntegrationTest(t, "call-aws-service-mwaa", validateStateMachineSucceeds)
 }
 
 // Run the apps/call-aws-service-mediapackagevod.ts integration test
 func TestCallAwsServiceMediapackagevod(t *testing.T) {
-	runStepfunctionsIntegrationTest(t, "call-aws-service-mediapackagevod", "us-east-1", validateStateMachineSucceeds)
+	runStepfunctionsIntegrationTest(t, "call-aws-service-mediapackagevod", validateStateMachineSucceeds)
 }
 
 // Run the apps/call-aws-service-logs.ts integration test
 func TestCallAwsServiceLogs(t *testing.T) {
-	runStepfunctionsIntegrationTest(t, "call-aws-service-logs", "us-east-1", validateStateMachineSucceeds)
+	runStepfunctionsIntegrationTest(t, "call-aws-service-logs", validateStateMachineSucceeds)
 }
 
 // Run the apps/call-aws-service-efs.ts integration test
 func TestCallAwsServiceEfs(t *testing.T) {
-	runStepfunctionsIntegrationTest(t, "call-aws-service-efs", "us-east-1", validateCallAwsServiceEfs)
+	runStepfunctionsIntegrationTest(t, "call-aws-service-efs", validateCallAwsServiceEfs)
 }
 
 // Run the apps/sqs-send-message.ts integration test
 func TestSqsSendMessage(t *testing.T) {
-	runStepfunctionsIntegrationTest(t, "sqs-send-message", "us-east-1", validateSqsSendMessage)
+	runStepfunctionsIntegrationTest(t, "sqs-send-message", validateSqsSendMessage)
 }
 
 // Run the apps/sfn-invoke-activity.ts integration test
 func TestSfnInvokeActivity(t *testing.T) {
-	runStepfunctionsIntegrationTest(t, "sfn-invoke-activity", "us-east-1", validateSfnInvokeActivity)
+	runStepfunctionsIntegrationTest(t, "sfn-invoke-activity", validateSfnInvokeActivity)
 }
 
 // Run the apps/sfn-start-execution.ts integration test
 func TestSfnStartExecution(t *testing.T) {
-	runStepfunctionsIntegrationTest(t, "sfn-start-execution", "us-east-1",
+	runStepfunctionsIntegrationTest(t, "sfn-start-execution",
 		func(t *testing.T, tfWorkingDir string, awsRegion string) {
 			message := "hello world!"
 			input := map[string]interface{}{
@@ -100,7 +106,7 @@ func TestLambdaInvokeFunction(t *testing.T) {
 	util.SaveSynthDependencies(t, tfWorkingDir, &map[string]string{
 		"@aws-sdk/client-sfn": "^3.682.0",
 	})
-	runStepfunctionsIntegrationTest(t, testApp, "us-east-1",
+	runStepfunctionsIntegrationTest(t, testApp,
 		func(t *testing.T, tfWorkingDir string, awsRegion string) {
 			input := map[string]any{
 				"guid": 1234,
@@ -111,46 +117,41 @@ func TestLambdaInvokeFunction(t *testing.T) {
 
 // Run the apps/lambda-invoke.ts integration test
 func TestLambdaInvoke(t *testing.T) {
-	runStepfunctionsIntegrationTest(t, "lambda-invoke", "us-east-1", validateStateMachineSucceeds)
+	runStepfunctionsIntegrationTest(t, "lambda-invoke", validateStateMachineSucceeds)
 }
 
 // Run the apps/lambda-invoke.payload.only.ts integration test
 func TestLambdaInvokePayloadOnly(t *testing.T) {
-	runStepfunctionsIntegrationTest(t, "lambda-invoke.payload.only", "us-east-1", validateStateMachineSucceeds)
+	runStepfunctionsIntegrationTest(t, "lambda-invoke.payload.only", validateStateMachineSucceeds)
 }
 
 // Run the apps/eventbridge-put-events.ts integration test
 func TestEventbridgePutEvents(t *testing.T) {
 	// https://github.com/aws/aws-cdk/blob/v2.164.1/packages/@aws-cdk-testing/framework-integ/test/aws-stepfunctions-tasks/test/eventbridge/integ.put-events.ts#L43
-	runStepfunctionsIntegrationTest(t, "eventbridge-put-events", "us-east-1", validateStateMachineSucceeds)
+	runStepfunctionsIntegrationTest(t, "eventbridge-put-events", validateStateMachineSucceeds)
 }
 
 // Validate the call-aws-service-efs integration test
 func validateCallAwsServiceEfs(t *testing.T, tfWorkingDir string, awsRegion string) {
-	terraformOptions := test_structure.LoadTerraformOptions(t, tfWorkingDir)
+	terraformOptions := util.LoadTerraformOptions(t, tfWorkingDir)
 	stateMachineArn := util.LoadOutputAttribute(t, terraformOptions, "state_machine", "arn")
 	efsAccessPointArn := terraform.OutputRequired(t, terraformOptions, "efs_accesspoint_arn")
-	// sleep for iam propagation
-	time.Sleep(5 * time.Second)
 
 	sampleInput := map[string]interface{}{
 		"pathToArn": efsAccessPointArn,
 		"pathToId":  "MYTAGVALUE",
 	}
-	executionArn := util.StartSfnExecution(t, awsRegion, stateMachineArn, sampleInput)
-	util.WaitForSfnExecutionStatus(t, awsRegion, *executionArn, types.ExecutionStatusSucceeded, 12, 5*time.Second)
+	runExecutionEventually(t, awsRegion, stateMachineArn, sampleInput)
 }
 
 // Validate the sqs-send-message integration test
 func validateSqsSendMessage(t *testing.T, tfWorkingDir string, awsRegion string) {
 	terraformOptions := test_structure.LoadTerraformOptions(t, tfWorkingDir)
-	stateMachineArn := util.LoadOutputAttribute(t, terraformOptions, "state_machine", "arn")
-	queueUrl := util.LoadOutputAttribute(t, terraformOptions, "queue", "url")
-	// sleep for iam propagation
-	time.Sleep(5 * time.Second)
+	outputs := integ.LoadOutputs(t, terraformOptions)
+	stateMachineArn := outputs.String(t, "state_machine.arn")
+	queueUrl := outputs.String(t, "queue.url")
 
-	executionArn := util.StartSfnExecution(t, awsRegion, stateMachineArn, nil)
-	util.WaitForSfnExecutionStatus(t, awsRegion, *executionArn, types.ExecutionStatusSucceeded, 12, 5*time.Second)
+	runExecutionEventually(t, awsRegion, stateMachineArn, nil)
 	// validate sqs message
 	resp := util.WaitForQueueMessage(t, awsRegion, queueUrl, 20)
 	terratestLogger.Logf(t, "Message Body: %v", resp.MessageBody)
@@ -165,9 +166,15 @@ func validateSqsSendMessage(t *testing.T, tfWorkingDir string, awsRegion string)
 func validateSfnInvokeActivity(t *testing.T, tfWorkingDir string, awsRegion string) {
 	workerName := "terratest_worker"
 	terraformOptions := test_structure.LoadTerraformOptions(t, tfWorkingDir)
-	stateMachineArn := util.LoadOutputAttribute(t, terraformOptions, "state_machine", "arn")
-	submitJobActivity := util.LoadOutputAttribute(t, terraformOptions, "submit_job_activity", "arn")
-	checkJobActivity := util.LoadOutputAttribute(t, terraformOptions, "check_job_activity", "arn")
+	var outputs struct {
+		StateMachineArn   string `jmes:"state_machine.arn"`
+		SubmitJobActivity string `jmes:"submit_job_activity.arn"`
+		CheckJobActivity  string `jmes:"check_job_activity.arn"`
+	}
+	integ.LoadOutputs(t, terraformOptions).Bind(t, &outputs)
+	stateMachineArn := outputs.StateMachineArn
+	submitJobActivity := outputs.SubmitJobActivity
+	checkJobActivity := outputs.CheckJobActivity
 
 	waitTime := 10 // NOTE: we poll for activity 5s before poller resumes
 	executionArn := util.StartSfnExecution(t, awsRegion, stateMachineArn, map[string]string{
@@ -219,17 +226,10 @@ func validateStateMachineSucceeds(t *testing.T, tfWorkingDir string, awsRegion s
 	// Load the Terraform Options saved by the earlier deploy_terraform stage
 	terraformOptions := test_structure.LoadTerraformOptions(t, tfWorkingDir)
 	stateMachineArn := util.LoadOutputAttribute(t, terraformOptions, "state_machine", "arn")
-	// sleep for iam propagation
-	time.Sleep(5 * time.Second)
 
 	// https://github.com/aws/aws-cdk/blob/v2.164.1/packages/%40aws-cdk-testing/framework-integ/test/aws-stepfunctions-tasks/test/aws-sdk/integ.call-aws-service-sfn.ts#L35
 	// https://github.com/aws/aws-cdk/blob/v2.164.1/packages/%40aws-cdk-testing/framework-integ/test/aws-stepfunctions-tasks/test/lambda/integ.invoke.ts#L99
-	executionArn := util.StartSfnExecution(t, awsRegion, stateMachineArn, nil)
-	util.WaitForSfnExecutionStatus(t, awsRegion, *executionArn,
-		types.ExecutionStatusSucceeded,
-		3,
-		3*time.Second,
-	)
+	runExecutionEventually(t, awsRegion, stateMachineArn, nil)
 }
 
 // Validate state machine execution succeeds after starting and asserts output
@@ -237,14 +237,7 @@ func validateStateMachineSucceedsWithOutput(t *testing.T, tfWorkingDir string, a
 	// Load the Terraform Options saved by the earlier deploy_terraform stage
 	terraformOptions := test_structure.LoadTerraformOptions(t, tfWorkingDir)
 	stateMachineArn := util.LoadOutputAttribute(t, terraformOptions, "state_machine", "arn")
-	// sleep for iam propagation
-	time.Sleep(5 * time.Second)
-	executionArn := util.StartSfnExecution(t, awsRegion, stateMachineArn, input)
-	result := util.WaitForSfnExecutionStatus(t, awsRegion, *executionArn,
-		types.ExecutionStatusSucceeded,
-		3,
-		3*time.Second,
-	)
+	result := runExecutionEventually(t, awsRegion, stateMachineArn, input)
 	require.NotNil(t, result.Output)
 	var output map[string]interface{}
 	err := json.Unmarshal([]byte(result.Output), &output)
@@ -252,26 +245,50 @@ func validateStateMachineSucceedsWithOutput(t *testing.T, tfWorkingDir string, a
 	integ.Assert(t, output, assertions)
 }
 
-// run stepfunctions integration test
-func runStepfunctionsIntegrationTest(t *testing.T, testApp, awsRegion string, validate func(t *testing.T, tfWorkingDir string, awsRegion string)) {
-	t.Parallel()
-	tfWorkingDir := filepath.Join("tf", testApp)
-	envVars := executors.EnvMap(os.Environ())
-	envVars["AWS_REGION"] = awsRegion
-	envVars["ENVIRONMENT_NAME"] = "test"
-	envVars["STACK_NAME"] = testApp
+// runExecutionEventually starts an execution of the state machine and waits for it to stop. Executions fail until the
+// IAM policies of the deploy have propagated, a failed execution is started again up to maxExecutions times.
+func runExecutionEventually(t *testing.T, awsRegion, stateMachineArn string, input any) *util.SfnExecutionOutput {
+	var result *util.SfnExecutionOutput
+	for execution := 1; execution <= maxExecutions; execution++ {
+		executionArn := util.StartSfnExecution(t, awsRegion, stateMachineArn, input)
+		result = waitForExecutionStopped(t, awsRegion, *executionArn)
+		if result.Status == types.ExecutionStatusSucceeded {
+			return result
+		}
+		terratestLogger.Logf(t, "Execution %d of %d stopped with status %s: %s, cause: %s", execution, maxExecutions, result.Status, result.Error, result.Cause)
+	}
+	t.Fatalf("state machine did not succeed in %d executions, last status %s: %s, cause: %s", maxExecutions, result.Status, result.Error, result.Cause)
+	return nil
+}
 
-	defer test_structure.RunTestStage(t, "cleanup_terraform", func() {
-		util.UndeployUsingTerraform(t, tfWorkingDir)
-	})
+// waitForExecutionStopped polls the status of the execution until it stops, fails the test after executionStopped
+func waitForExecutionStopped(t *testing.T, awsRegion, executionArn string) *util.SfnExecutionOutput {
+	result := &util.SfnExecutionOutput{}
+	stopped := fmt.Sprintf("^(%s|%s|%s|%s)$", types.ExecutionStatusSucceeded, types.ExecutionStatusFailed, types.ExecutionStatusTimedOut, types.ExecutionStatusAborted)
+	integ.AssertEventually(t, func() (any, error) {
+		resp, err := util.DescribeSfnExecutionE(t, awsRegion, executionArn)
+		if err != nil {
+			return nil, err
+		}
+		result = &util.SfnExecutionOutput{
+			Status: resp.Status,
+			Cause:  awssdk.ToString(resp.Cause),
+			Error:  awssdk.ToString(resp.Error),
+			Output: awssdk.ToString(resp.Output),
+		}
+		return result, nil
+	}, []integ.Assertion{{Path: "Status", ExpectedRegexp: &stopped}}, executionStopped)
+	if t.Failed() {
+		t.FailNow()
+	}
+	return result
+}
 
-	test_structure.RunTestStage(t, "synth_app", func() {
-		util.SynthApp(t, testApp, tfWorkingDir, envVars, "handlers")
-	})
-	test_structure.RunTestStage(t, "deploy_terraform", func() {
-		util.DeployUsingTerraform(t, tfWorkingDir, nil)
-	})
-	test_structure.RunTestStage(t, "validate", func() {
-		validate(t, tfWorkingDir, awsRegion)
+// run stepfunctions integration test
+func runStepfunctionsIntegrationTest(t *testing.T, testApp string, validate integ.Validator) {
+	integ.Run(t, integ.Scenario{
+		App:      testApp,
+		AppDirs:  []string{"handlers"},
+		Validate: validate,
 	})
 }
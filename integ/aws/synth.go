@@ -0,0 +1,287 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/environment-toolkit/go-synth"
+	"github.com/environment-toolkit/go-synth/executors"
+	"github.com/environment-toolkit/go-synth/models"
+	"github.com/spf13/afero"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// DefaultAppsDir is the directory of the test apps relative to the integration namespace
+	DefaultAppsDir = "apps"
+	// DefaultPackageName is the package name the test apps import the library as
+	DefaultPackageName = "@envtio/base"
+)
+
+// ExecutorFactory creates the go-synth executor running the test app.
+type ExecutorFactory struct {
+	New     models.NewExecutorFn // Executor constructor
+	Options map[string]string    // Executor options, i.e. `packageManager` and `entrypoint` of the node executor
+}
+
+var (
+	// BunExecutor runs the test app with bun (default)
+	BunExecutor = ExecutorFactory{New: executors.NewBunExecutor}
+	// PnpmExecutor runs the test app with node and pnpm
+	PnpmExecutor = ExecutorFactory{New: executors.NewNodeExecutor}
+	// NpmExecutor runs the test app with node and npm
+	NpmExecutor = ExecutorFactory{
+		New: executors.NewNodeExecutor,
+		Options: map[string]string{
+			"packageManager": "npm@10.8.2",
+			"entrypoint":     "npm",
+		},
+	}
+)
+
+// SynthOptions configures SynthAppWithOptions, zero values default to the layout of the integration namespaces.
+type SynthOptions struct {
+	TestApp           string              // Name of the test app, required
+	TfWorkingDir      string              // Directory the synthesized stack is copied to, required
+	Env               map[string]string   // Environment variables of the synth process, defaults to the os environment
+	AdditionalAppDirs []string            // Directories relative to AppsDir copied to the synth app fs, i.e. `handlers`
+	Executor          *ExecutorFactory    // Executor running the test app, defaults to BunExecutor
+	AppsDir           string              // Directory of the test apps, defaults to DefaultAppsDir
+	EntryPath         string              // Path of the test app entry, defaults to `<AppsDir>/<TestApp>.ts`
+	StackName         string              // Name of the synthesized stack in `cdktf.out/stacks`, defaults to TestApp
+	AllStacks         bool                // Copy the manifest and all stacks to TfWorkingDir instead of StackName, see SynthStacks
+	Variables         Variables           // Go template variables applied to the test app source if not nil, see RunMatrix
+	RepoRoot          string              // Path to the library repo root, defaults to the root of this repo
+	PackageName       string              // Package name of the library in the test app, defaults to DefaultPackageName
+	PackagePath       string              // Relative path the library is copied to in the synth app fs
+	CopyOptions       *models.CopyOptions // Options copying the library and AdditionalAppDirs, defaults to skipping integ, src, node_modules, ...
+	Dependencies      map[string]string   // Dependency overrides, applied after the saved synth dependencies, i.e. a published PackageName version
+	Timeout           time.Duration       // Timeout of the synth, no timeout if zero
+	CacheDir          string              // Directory of cached synth outputs, defaults to $SYNTH_CACHE_DIR or the user cache dir
+	CacheEnv          []string            // Inherited env vars read by the test app, in the cache key in addition to DefaultSynthCacheEnv
+	FailOnWarnings    bool                // Fail on warning annotations, i.e. construct deprecations. Error annotations always fail
+	DisableCache      bool                // Always run the executor, also disabled by SYNTH_CACHE=false
+}
+
+// withDefaults returns a copy of the options with all defaults set
+func (o SynthOptions) withDefaults() SynthOptions {
+	if o.Executor == nil {
+		o.Executor = &BunExecutor
+	}
+	if o.AppsDir == "" {
+		o.AppsDir = DefaultAppsDir
+	}
+	if o.EntryPath == "" {
+		o.EntryPath = filepath.Join(o.AppsDir, o.TestApp+".ts")
+	}
+	if o.StackName == "" {
+		o.StackName = o.TestApp
+	}
+	if o.RepoRoot == "" {
+		o.RepoRoot = repoRoot
+	}
+	if o.PackageName == "" {
+		o.PackageName = DefaultPackageName
+	}
+	if o.PackagePath == "" {
+		o.PackagePath = relPath
+	}
+	if o.CopyOptions == nil {
+		o.CopyOptions = &defaultCopyOptions
+	}
+	return o
+}
+
+// srcImportPath returns the path test apps import the library source from, relative to the entry dir
+func (o SynthOptions) srcImportPath() (string, error) {
+	return filepath.Rel(filepath.Dir(o.EntryPath), filepath.Join(o.RepoRoot, "src"))
+}
+
+// SynthAppWithOptions synths a test app and copies the stack to the Terraform working dir.
+// Returns the console output and annotations of the synth. This fails the test on any errors
+func SynthAppWithOptions(t *testing.T, opts SynthOptions) *SynthResult {
+	result, err := SynthAppWithOptionsE(t, opts)
+	if err != nil {
+		t.Fatal("Failed to synth app", err)
+	}
+	return result
+}
+
+// SynthAppWithOptionsE synths a test app and copies the stack to the Terraform working dir.
+// Returns the console output and annotations of the synth, also if the synth fails.
+//
+// The library is copied from RepoRoot and replaces the `src` imports of the test app, unless Dependencies
+// override PackageName with another version.
+func SynthAppWithOptionsE(t *testing.T, opts SynthOptions) (*SynthResult, error) {
+	if opts.TestApp == "" || opts.TfWorkingDir == "" {
+		return nil, fmt.Errorf("TestApp and TfWorkingDir are required")
+	}
+	opts = opts.withDefaults()
+
+	// capture the executor output in addition to forwarding it to the test log
+	output := &synthOutputCore{}
+	zapLogger := ForwardingLogger(t, terratestLogger).WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(c, output)
+	}))
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	mainTsBytes, err := os.ReadFile(opts.EntryPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", opts.EntryPath, err)
+	}
+
+	// load dependencies to Synth app
+	var synthDependencies map[string]string
+	LoadSynthDependencies(t, opts.TfWorkingDir, &synthDependencies)
+	if synthDependencies == nil {
+		synthDependencies = make(map[string]string)
+	}
+	synthDependencies[opts.PackageName] = opts.PackagePath
+	maps.Copy(synthDependencies, opts.Dependencies)
+	useLocalPackage := synthDependencies[opts.PackageName] == opts.PackagePath
+	if useLocalPackage {
+		if _, err := os.Stat(filepath.Join(opts.RepoRoot, "lib")); err != nil {
+			return nil, fmt.Errorf("no lib folder in '%s', run pnpm compile before go test", opts.RepoRoot)
+		}
+	}
+
+	thisFs := afero.NewOsFs()
+	app := synth.NewApp(opts.Executor.New, zapLogger)
+	err = app.Configure(ctx, models.AppConfig{
+		EnvVars: opts.Env,
+		// copy additionalDirs and the library to synth App fs
+		PreSetupFn: func(e models.Executor) error {
+			for _, dirName := range opts.AdditionalAppDirs {
+				relDir := filepath.Join(opts.AppsDir, dirName)
+				if err := e.CopyFrom(ctx, thisFs, relDir, dirName, *opts.CopyOptions); err != nil {
+					return err
+				}
+			}
+			if !useLocalPackage {
+				return nil
+			}
+			return e.CopyFrom(ctx, thisFs, opts.RepoRoot, opts.PackagePath, *opts.CopyOptions)
+		},
+		Dependencies:    synthDependencies,
+		ExecutorOptions: maps.Clone(opts.Executor.Options),
+	})
+	if err != nil {
+		return nil, err
+	}
+	// replace the path to src with the library package
+	srcImportPath, err := opts.srcImportPath()
+	if err != nil {
+		return nil, err
+	}
+	mainTs := strings.ReplaceAll(string(mainTsBytes), srcImportPath, opts.PackageName)
+	if opts.Variables != nil {
+		if mainTs, err = renderApp(opts.EntryPath, mainTs, opts.Variables); err != nil {
+			return nil, fmt.Errorf("failed to template %s: %v", opts.EntryPath, err)
+		}
+	}
+
+	// the whole cdktf.out is synthesized to outputDir, which is a cache entry unless the cache is disabled
+	var outputDir string
+	cacheDir := opts.synthCacheDir()
+	if cacheDir == "" {
+		if outputDir, err = os.MkdirTemp("", "synth-"+opts.TestApp+"-"); err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(outputDir)
+		if err := evalApp(ctx, app, thisFs, mainTs, opts, output, outputDir); err != nil {
+			return output.result(), err
+		}
+	} else {
+		cacheKey, err := opts.synthCacheKey(mainTs, synthDependencies, useLocalPackage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute synth cache key: %v", err)
+		}
+		outputDir = filepath.Join(cacheDir, cacheKey)
+		if _, err := os.Stat(filepath.Join(outputDir, ManifestFileName)); err == nil {
+			terratestLogger.Logf(t, "Synth cache hit for %s (%s), skipping synth", opts.TestApp, cacheKey[:12])
+			if err := touchSynthCacheEntry(outputDir); err != nil {
+				terratestLogger.Logf(t, "Failed to touch synth cache entry %s: %v", outputDir, err)
+			}
+		} else {
+			terratestLogger.Logf(t, "Synth cache miss for %s (%s)", opts.TestApp, cacheKey[:12])
+			if err := evalToCache(ctx, app, thisFs, mainTs, opts, output, cacheDir, cacheKey); err != nil {
+				return output.result(), err
+			}
+			if err := pruneSynthCache(cacheDir); err != nil {
+				terratestLogger.Logf(t, "Failed to prune synth cache %s: %v", cacheDir, err)
+			}
+		}
+	}
+
+	result, err := loadSynthResult(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.copySynthOutput(outputDir); err != nil {
+		return result, err
+	}
+	result.Log(t)
+	return result, result.Err(opts.FailOnWarnings)
+}
+
+// evalToCache synths to a temporary cache entry, renamed once complete so parallel tests never read partial entries
+func evalToCache(ctx context.Context, app synth.App, thisFs afero.Fs, mainTs string, opts SynthOptions, output *synthOutputCore, cacheDir, cacheKey string) error {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return err
+	}
+	tmpEntry, err := os.MkdirTemp(cacheDir, cacheKey+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpEntry)
+	if err := evalApp(ctx, app, thisFs, mainTs, opts, output, tmpEntry); err != nil {
+		return err
+	}
+	cacheEntry := filepath.Join(cacheDir, cacheKey)
+	if err := os.Rename(tmpEntry, cacheEntry); err != nil {
+		// a parallel test stored the same entry first
+		if _, statErr := os.Stat(cacheEntry); statErr != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evalApp runs the executor and copies cdktf.out with the captured console output to dstPath
+func evalApp(ctx context.Context, app synth.App, thisFs afero.Fs, mainTs string, opts SynthOptions, output *synthOutputCore, dstPath string) error {
+	if err := app.Eval(ctx, thisFs, mainTs, "cdktf.out", dstPath); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("synth of '%s' timed out after %s: %v", opts.TestApp, opts.Timeout, err)
+		}
+		return fmt.Errorf("synth of '%s' failed: %v%s", opts.TestApp, err, output.result().stderrTail())
+	}
+	return output.result().writeConsoleOutput(dstPath)
+}
+
+// copySynthOutput copies the stack, or the manifest and all stacks, from the synthesized cdktf.out to TfWorkingDir.
+//
+// Files generated by an earlier synth (i.e. `assets` of a cached entry) are removed first, state and test data are kept.
+func (o SynthOptions) copySynthOutput(outputDir string) error {
+	srcDir := outputDir
+	if !o.AllStacks {
+		srcDir = filepath.Join(outputDir, "stacks", o.StackName)
+		if _, err := os.Stat(srcDir); err != nil {
+			return fmt.Errorf("stack '%s' not found in synth output of '%s'", o.StackName, o.TestApp)
+		}
+	}
+	if err := removeGeneratedFiles(srcDir, o.TfWorkingDir); err != nil {
+		return err
+	}
+	return copyDir(srcDir, o.TfWorkingDir)
+}
@@ -0,0 +1,255 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/environment-toolkit/go-synth/executors"
+	"github.com/hashicorp/go-multierror"
+)
+
+const (
+	// SynthCacheDirEnvVar overrides the synth cache dir, defaults to `<user cache dir>/envtio-base/synth`
+	SynthCacheDirEnvVar = "SYNTH_CACHE_DIR"
+	// SynthCacheEnvVar disables the synth cache if set to `false`
+	SynthCacheEnvVar = "SYNTH_CACHE"
+
+	// SynthCacheMaxAge is the age of unused cache entries removed after a cache miss
+	SynthCacheMaxAge = 7 * 24 * time.Hour
+	// SynthCacheMaxEntries is the number of most recently used cache entries kept after a cache miss
+	SynthCacheMaxEntries = 100
+
+	// synthCacheVersion invalidates all entries when the cache key or layout changes
+	synthCacheVersion = "3"
+)
+
+// DefaultSynthCacheEnv are the env vars read by the test apps, included in the synth cache key also if inherited from the os environment
+var DefaultSynthCacheEnv = []string{"AWS_REGION", "DNS_DOMAIN_NAME", "DNS_ZONE_ID", "ENVIRONMENT_NAME", "STACK_NAME"}
+
+// synthCacheDir returns the synth cache dir, empty if the cache is disabled
+func (o SynthOptions) synthCacheDir() string {
+	if o.DisableCache || os.Getenv(SynthCacheEnvVar) == "false" {
+		return ""
+	}
+	if o.CacheDir != "" {
+		return o.CacheDir
+	}
+	if dir := os.Getenv(SynthCacheDirEnvVar); dir != "" {
+		return dir
+	}
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(userCacheDir, "envtio-base", "synth")
+}
+
+// synthCacheKey returns a hash of all synth inputs: the test app source, the compiled library, additional app dirs,
+// dependencies, env vars read by the app (see synthCacheEnv) and executor options.
+func (o SynthOptions) synthCacheKey(mainTs string, dependencies map[string]string, useLocalPackage bool) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "version=%s\n", synthCacheVersion)
+	writeSortedMap(h, "dependency", dependencies)
+	writeSortedMap(h, "executor", o.Executor.Options)
+	writeSortedMap(h, "env", o.synthCacheEnv())
+	fmt.Fprintf(h, "main.ts=%d\n%s\n", len(mainTs), mainTs)
+
+	if useLocalPackage {
+		if err := hashFile(h, filepath.Join(o.RepoRoot, "package.json"), "package.json"); err != nil {
+			return "", err
+		}
+		if err := hashDir(h, filepath.Join(o.RepoRoot, "lib"), "lib", nil); err != nil {
+			return "", err
+		}
+	}
+	for _, dirName := range o.AdditionalAppDirs {
+		if err := hashDir(h, filepath.Join(o.AppsDir, dirName), dirName, o.CopyOptions.SkipDirs); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// synthCacheEnv returns the env vars of the synth in DefaultSynthCacheEnv or CacheEnv, and the vars set by the test
+// which are not inherited from the os environment. Other inherited vars (i.e. PATH, credentials or the `SKIP_*` stage
+// toggles) do not change the synth output and would invalidate the cache between shells and CI jobs.
+func (o SynthOptions) synthCacheEnv() map[string]string {
+	synthEnv := o.Env
+	if synthEnv == nil {
+		synthEnv = executors.EnvMap(os.Environ())
+	}
+	env := map[string]string{}
+	for k, v := range synthEnv {
+		if strings.HasPrefix(k, "SKIP_") {
+			continue
+		}
+		if slices.Contains(DefaultSynthCacheEnv, k) || slices.Contains(o.CacheEnv, k) {
+			env[k] = v
+		} else if inherited, ok := os.LookupEnv(k); !ok || inherited != v {
+			env[k] = v
+		}
+	}
+	return env
+}
+
+// pruneSynthCache removes cache entries and leftover temporary entries unused for SynthCacheMaxAge,
+// and the least recently used entries beyond SynthCacheMaxEntries. Cache hits touch their entry.
+func pruneSynthCache(cacheDir string) error {
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return err
+	}
+	type cacheEntry struct {
+		path    string
+		modTime time.Time
+	}
+	var kept []cacheEntry
+	var combinedErr error
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !e.IsDir() {
+			continue
+		}
+		path := filepath.Join(cacheDir, e.Name())
+		if time.Since(info.ModTime()) > SynthCacheMaxAge {
+			if err := os.RemoveAll(path); err != nil {
+				combinedErr = multierror.Append(combinedErr, err)
+			}
+			continue
+		}
+		if !strings.Contains(e.Name(), ".tmp-") {
+			kept = append(kept, cacheEntry{path: path, modTime: info.ModTime()})
+		}
+	}
+	if len(kept) <= SynthCacheMaxEntries {
+		return combinedErr
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].modTime.After(kept[j].modTime) })
+	for _, e := range kept[SynthCacheMaxEntries:] {
+		if err := os.RemoveAll(e.path); err != nil {
+			combinedErr = multierror.Append(combinedErr, err)
+		}
+	}
+	return combinedErr
+}
+
+// touchSynthCacheEntry marks the cache entry as recently used
+func touchSynthCacheEntry(entry string) error {
+	now := time.Now()
+	return os.Chtimes(entry, now, now)
+}
+
+func writeSortedMap(w io.Writer, prefix string, m map[string]string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s:%s=%q\n", prefix, k, m[k])
+	}
+}
+
+// hashDir hashes the relative path and contents of all files in dir, skipping directories by name
+func hashDir(w io.Writer, dir, name string, skipDirs []string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && slices.Contains(skipDirs, d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return hashFile(w, path, filepath.Join(name, rel))
+	})
+}
+
+func hashFile(w io.Writer, path, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "file:%s=%d\n", filepath.ToSlash(name), info.Size())
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// removeGeneratedFiles removes the files of src and the `assets` dirs of the stacks from dst, so no stale files of an
+// earlier synth remain. Other dirs are walked, files which are not in src (i.e. state files or test data) are kept.
+func removeGeneratedFiles(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != "assets" {
+			if err := removeGeneratedFiles(filepath.Join(src, e.Name()), filepath.Join(dst, e.Name())); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dst, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyDir copies all files of src into dst, overwriting existing files
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,171 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSynthOptions_SynthCacheDir(t *testing.T) {
+	t.Setenv(SynthCacheEnvVar, "")
+	t.Setenv(SynthCacheDirEnvVar, "/tmp/from-env")
+	assert.Equal(t, "/tmp/from-env", SynthOptions{}.synthCacheDir())
+	assert.Equal(t, "/tmp/from-options", SynthOptions{CacheDir: "/tmp/from-options"}.synthCacheDir())
+	assert.Empty(t, SynthOptions{DisableCache: true}.synthCacheDir())
+
+	t.Setenv(SynthCacheEnvVar, "false")
+	assert.Empty(t, SynthOptions{CacheDir: "/tmp/from-options"}.synthCacheDir())
+}
+
+func TestSynthOptions_SynthCacheKey(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "package.json"), `{"name": "@envtio/base"}`)
+	writeTestFile(t, filepath.Join(root, "lib", "index.js"), "exports.a = 1;")
+	appsDir := filepath.Join(root, "apps")
+	writeTestFile(t, filepath.Join(appsDir, "handlers", "index.ts"), "export const handler = 1;")
+	writeTestFile(t, filepath.Join(appsDir, "handlers", "node_modules", "dep", "index.js"), "ignored")
+
+	opts := SynthOptions{
+		TestApp:           "app",
+		TfWorkingDir:      "tf/app",
+		Env:               map[string]string{"ENVIRONMENT_NAME": "test", "SKIP_deploy_terraform": "true"},
+		AdditionalAppDirs: []string{"handlers"},
+		AppsDir:           appsDir,
+		RepoRoot:          root,
+	}.withDefaults()
+	deps := map[string]string{"@envtio/base": relPath}
+	key := func(opts SynthOptions, mainTs string) string {
+		k, err := opts.synthCacheKey(mainTs, deps, true)
+		require.NoError(t, err)
+		return k
+	}
+	base := key(opts, "main")
+	assert.Len(t, base, 64)
+	assert.Equal(t, base, key(opts, "main"), "key must be stable")
+
+	t.Run("stage toggles", func(t *testing.T) {
+		o := opts
+		o.Env = map[string]string{"ENVIRONMENT_NAME": "test"}
+		assert.Equal(t, base, key(o, "main"))
+	})
+	t.Run("skipped dirs", func(t *testing.T) {
+		writeTestFile(t, filepath.Join(appsDir, "handlers", "node_modules", "dep", "index.js"), "changed")
+		assert.Equal(t, base, key(opts, "main"))
+	})
+	t.Run("app source", func(t *testing.T) {
+		assert.NotEqual(t, base, key(opts, "changed"))
+	})
+	t.Run("env", func(t *testing.T) {
+		o := opts
+		o.Env = map[string]string{"ENVIRONMENT_NAME": "renamed"}
+		assert.NotEqual(t, base, key(o, "main"))
+	})
+	t.Run("inherited env", func(t *testing.T) {
+		t.Setenv("TERM_SESSION_ID", "session-1")
+		o := opts
+		o.Env = map[string]string{"ENVIRONMENT_NAME": "test", "TERM_SESSION_ID": "session-1"}
+		assert.Equal(t, base, key(o, "main"), "Expected inherited env vars not read by the app to be ignored")
+		o.Env["TERM_SESSION_ID"] = "overridden"
+		assert.NotEqual(t, base, key(o, "main"), "Expected env vars set by the test to be included")
+		o.Env["TERM_SESSION_ID"] = "session-1"
+		o.CacheEnv = []string{"TERM_SESSION_ID"}
+		assert.NotEqual(t, base, key(o, "main"), "Expected CacheEnv vars to be included")
+	})
+	t.Run("executor", func(t *testing.T) {
+		o := opts
+		o.Executor = &NpmExecutor
+		assert.NotEqual(t, base, key(o, "main"))
+	})
+	t.Run("dependencies", func(t *testing.T) {
+		k, err := opts.synthCacheKey("main", map[string]string{"@envtio/base": "0.1.0"}, false)
+		require.NoError(t, err)
+		assert.NotEqual(t, base, k)
+	})
+	t.Run("lib", func(t *testing.T) {
+		writeTestFile(t, filepath.Join(root, "lib", "index.js"), "exports.a = 2;")
+		assert.NotEqual(t, base, key(opts, "main"))
+	})
+	t.Run("app dirs", func(t *testing.T) {
+		before := key(opts, "main")
+		writeTestFile(t, filepath.Join(appsDir, "handlers", "index.ts"), "export const handler = 2;")
+		assert.NotEqual(t, before, key(opts, "main"))
+	})
+}
+
+func TestCopyDir(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "cdk.tf.json"), "{}")
+	writeTestFile(t, filepath.Join(src, "assets", "handler", "index.zip"), "zip")
+	dst := t.TempDir()
+	writeTestFile(t, filepath.Join(dst, "cdk.tf.json"), `{"old": true}`)
+	writeTestFile(t, filepath.Join(dst, "app.tfstate"), "state")
+
+	require.NoError(t, copyDir(src, dst))
+	for path, expected := range map[string]string{
+		"cdk.tf.json":              "{}",
+		"assets/handler/index.zip": "zip",
+		"app.tfstate":              "state",
+	} {
+		actual, err := os.ReadFile(filepath.Join(dst, path))
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(actual), path)
+	}
+}
+
+func TestRemoveGeneratedFiles(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "manifest.json"), "{}")
+	writeTestFile(t, filepath.Join(src, "stacks", "app", "cdk.tf.json"), "{}")
+	writeTestFile(t, filepath.Join(src, "stacks", "app", "assets", "handler", "new.zip"), "zip")
+	dst := t.TempDir()
+	writeTestFile(t, filepath.Join(dst, "manifest.json"), `{"old": true}`)
+	writeTestFile(t, filepath.Join(dst, "stacks", "app", "cdk.tf.json"), `{"old": true}`)
+	writeTestFile(t, filepath.Join(dst, "stacks", "app", "assets", "handler", "old.zip"), "stale")
+	writeTestFile(t, filepath.Join(dst, "stacks", "app", "app.tfstate"), "state")
+	writeTestFile(t, filepath.Join(dst, ".test-data", "TerraformOptions.json"), "{}")
+
+	require.NoError(t, removeGeneratedFiles(src, dst))
+	for _, path := range []string{"manifest.json", "stacks/app/cdk.tf.json", "stacks/app/assets"} {
+		assert.NoFileExists(t, filepath.Join(dst, path))
+	}
+	assert.FileExists(t, filepath.Join(dst, "stacks", "app", "app.tfstate"))
+	assert.FileExists(t, filepath.Join(dst, ".test-data", "TerraformOptions.json"))
+}
+
+func TestPruneSynthCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	entry := func(name string, age time.Duration) string {
+		path := filepath.Join(cacheDir, name)
+		writeTestFile(t, filepath.Join(path, ManifestFileName), "{}")
+		modTime := time.Now().Add(-age)
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+		return path
+	}
+	expired := entry("expired", SynthCacheMaxAge+time.Hour)
+	leftover := entry("key.tmp-123", SynthCacheMaxAge+time.Hour)
+	var recent []string
+	for i := 0; i <= SynthCacheMaxEntries; i++ {
+		recent = append(recent, entry(fmt.Sprintf("recent-%03d", i), time.Duration(i)*time.Minute))
+	}
+
+	require.NoError(t, pruneSynthCache(cacheDir))
+	assert.NoDirExists(t, expired)
+	assert.NoDirExists(t, leftover)
+	assert.DirExists(t, recent[0])
+	assert.NoDirExists(t, recent[SynthCacheMaxEntries], "Expected the least recently used entry to be removed")
+
+	require.NoError(t, touchSynthCacheEntry(recent[SynthCacheMaxEntries-1]))
+	info, err := os.Stat(recent[SynthCacheMaxEntries-1])
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), info.ModTime(), time.Minute)
+}
+
+func writeTestFile(t *testing.T, path, contents string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+}
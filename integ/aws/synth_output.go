@@ -0,0 +1,177 @@
+package aws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/envtio/base/integ/redact"
+	"github.com/hashicorp/go-multierror"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// Annotation levels of the cdktf manifest
+	AnnotationInfo  = "@cdktf/info"
+	AnnotationWarn  = "@cdktf/warn"
+	AnnotationError = "@cdktf/error"
+
+	// consoleOutputFileName stores the captured console output next to the manifest, so cache hits report it as well
+	consoleOutputFileName = "console-output.json"
+	// stderrTailLines is the number of stderr lines included in synth errors
+	stderrTailLines = 20
+)
+
+// SynthResult is the console output and the annotations of a synth.
+type SynthResult struct {
+	Stdout      []string          `json:"stdout"` // Lines written to stdout by the executor, i.e. console.log of the test app
+	Stderr      []string          `json:"stderr"` // Lines written to stderr by the executor, i.e. console.warn of the test app
+	Annotations []StackAnnotation `json:"-"`      // Annotations of all stacks, sorted by stack and construct path
+}
+
+// StackAnnotation is an Annotation of a synthesized stack.
+type StackAnnotation struct {
+	Annotation
+	Stack string
+}
+
+func (a StackAnnotation) String() string {
+	return fmt.Sprintf("[%s] %s: %s", strings.TrimPrefix(a.Level, "@cdktf/"), a.ConstructPath, a.Message)
+}
+
+// Warnings returns the warning annotations.
+func (r *SynthResult) Warnings() []StackAnnotation {
+	return r.annotationsWithLevel(AnnotationWarn)
+}
+
+// Errors returns the error annotations.
+func (r *SynthResult) Errors() []StackAnnotation {
+	return r.annotationsWithLevel(AnnotationError)
+}
+
+func (r *SynthResult) annotationsWithLevel(level string) []StackAnnotation {
+	var result []StackAnnotation
+	for _, a := range r.Annotations {
+		if a.Level == level {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// Log reports the annotations and stderr output through t.Log.
+func (r *SynthResult) Log(t *testing.T) {
+	for _, line := range r.Stderr {
+		t.Log(redact.String("synth stderr: " + line))
+	}
+	for _, a := range r.Annotations {
+		t.Log(redact.String(a.String()))
+	}
+	t.Logf("Synth reported %d error(s), %d warning(s), %d stderr line(s)", len(r.Errors()), len(r.Warnings()), len(r.Stderr))
+}
+
+// Err returns an error listing the error annotations, and the warning annotations if failOnWarnings is set.
+func (r *SynthResult) Err(failOnWarnings bool) error {
+	failures := r.Errors()
+	if failOnWarnings {
+		failures = append(failures, r.Warnings()...)
+	}
+	var combinedErr error
+	for _, a := range failures {
+		combinedErr = multierror.Append(combinedErr, errors.New(a.String()))
+	}
+	return combinedErr
+}
+
+// stderrTail returns the last stderr lines to append to a synth error
+func (r *SynthResult) stderrTail() string {
+	if len(r.Stderr) == 0 {
+		return ""
+	}
+	tail := r.Stderr[max(0, len(r.Stderr)-stderrTailLines):]
+	return redact.String("\nstderr:\n  " + strings.Join(tail, "\n  "))
+}
+
+// writeConsoleOutput writes the console output to the synth output dir
+func (r *SynthResult) writeConsoleOutput(outputDir string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outputDir, consoleOutputFileName), data, 0644)
+}
+
+// loadSynthResult reads the console output and the manifest annotations of a synthesized cdktf.out
+func loadSynthResult(outputDir string) (*SynthResult, error) {
+	result := &SynthResult{}
+	if data, err := os.ReadFile(filepath.Join(outputDir, consoleOutputFileName)); err == nil {
+		if err := json.Unmarshal(data, result); err != nil {
+			return nil, fmt.Errorf("error parsing %s: %v", consoleOutputFileName, err)
+		}
+	}
+	stacks, err := LoadStacksE(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range stacks.Order {
+		for _, a := range stacks.Manifest.Stacks[name].Annotations {
+			result.Annotations = append(result.Annotations, StackAnnotation{Annotation: a, Stack: name})
+		}
+	}
+	sort.SliceStable(result.Annotations, func(i, j int) bool {
+		if result.Annotations[i].Stack != result.Annotations[j].Stack {
+			return result.Annotations[i].Stack < result.Annotations[j].Stack
+		}
+		return result.Annotations[i].ConstructPath < result.Annotations[j].ConstructPath
+	})
+	return result, nil
+}
+
+// synthOutputCore captures the executor output streamed to the zap logger: stdout lines are logged at info level,
+// stderr lines at warn level, both without fields
+type synthOutputCore struct {
+	mu     sync.Mutex
+	stdout []string
+	stderr []string
+}
+
+func (c *synthOutputCore) Enabled(zapcore.Level) bool { return true }
+
+func (c *synthOutputCore) With([]zapcore.Field) zapcore.Core { return c }
+
+func (c *synthOutputCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(e, c)
+}
+
+func (c *synthOutputCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if len(fields) > 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch entry.Level {
+	case zapcore.InfoLevel:
+		c.stdout = append(c.stdout, entry.Message)
+	case zapcore.WarnLevel:
+		c.stderr = append(c.stderr, entry.Message)
+	}
+	return nil
+}
+
+func (c *synthOutputCore) Sync() error { return nil }
+
+// result returns the captured output
+func (c *synthOutputCore) result() *SynthResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &SynthResult{
+		Stdout: append([]string{}, c.stdout...),
+		Stderr: append([]string{}, c.stderr...),
+	}
+}
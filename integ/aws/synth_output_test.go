@@ -0,0 +1,76 @@
+package aws
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSynthOutputCore(t *testing.T) {
+	output := &synthOutputCore{}
+	logger := zap.New(zapcore.NewTee(zapcore.NewNopCore(), output))
+	// executor output is streamed line by line without fields
+	logger.Info("synthesizing app")
+	logger.Warn("construct Bucket is deprecated")
+	logger.Info("templated", zap.String("template", "package.json"))
+	logger.Debug("copying file")
+
+	result := output.result()
+	assert.Equal(t, []string{"synthesizing app"}, result.Stdout)
+	assert.Equal(t, []string{"construct Bucket is deprecated"}, result.Stderr)
+	assert.Equal(t, "\nstderr:\n  construct Bucket is deprecated", result.stderrTail())
+}
+
+func TestLoadSynthResult(t *testing.T) {
+	outputDir := t.TempDir()
+	writeTestFile(t, filepath.Join(outputDir, ManifestFileName), `{
+  "version": "0.20.7",
+  "stacks": {
+    "app": {
+      "name": "app",
+      "workingDirectory": "stacks/app",
+      "annotations": [
+        {"constructPath": "app/Function", "level": "@cdktf/warn", "message": "runtime is deprecated"},
+        {"constructPath": "app/Bucket", "level": "@cdktf/info", "message": "bucket name is generated"}
+      ]
+    },
+    "certificate": {
+      "name": "certificate",
+      "workingDirectory": "stacks/certificate",
+      "annotations": [
+        {"constructPath": "certificate/Certificate", "level": "@cdktf/error", "message": "domain name is required"}
+      ]
+    }
+  }
+}`)
+	require.NoError(t, (&SynthResult{Stdout: []string{"done"}, Stderr: []string{"warning"}}).writeConsoleOutput(outputDir))
+
+	result, err := loadSynthResult(outputDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"done"}, result.Stdout)
+	assert.Equal(t, []string{"warning"}, result.Stderr)
+	var annotations []string
+	for _, a := range result.Annotations {
+		annotations = append(annotations, a.Stack+" "+a.String())
+	}
+	assert.Equal(t, []string{
+		"app [info] app/Bucket: bucket name is generated",
+		"app [warn] app/Function: runtime is deprecated",
+		"certificate [error] certificate/Certificate: domain name is required",
+	}, annotations)
+	assert.Len(t, result.Warnings(), 1)
+	assert.Len(t, result.Errors(), 1)
+
+	err = result.Err(false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "[error] certificate/Certificate: domain name is required")
+	assert.NotContains(t, err.Error(), "runtime is deprecated")
+	assert.Contains(t, result.Err(true).Error(), "[warn] app/Function: runtime is deprecated")
+
+	result.Log(t)
+	assert.NoError(t, (&SynthResult{}).Err(true))
+}
@@ -0,0 +1,73 @@
+package aws
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSynthOptions_Defaults(t *testing.T) {
+	opts := SynthOptions{TestApp: "url-rewrite-spa", TfWorkingDir: "tf/url-rewrite-spa"}.withDefaults()
+	assert.Equal(t, &BunExecutor, opts.Executor)
+	assert.Equal(t, "apps/url-rewrite-spa.ts", opts.EntryPath)
+	assert.Equal(t, DefaultPackageName, opts.PackageName)
+	assert.Equal(t, relPath, opts.PackagePath)
+	assert.Equal(t, &defaultCopyOptions, opts.CopyOptions)
+
+	// test apps import the library as `../../../../src`
+	srcImportPath, err := opts.srcImportPath()
+	require.NoError(t, err)
+	assert.Equal(t, "../../../../src", srcImportPath)
+}
+
+func TestSynthOptions_CustomLayout(t *testing.T) {
+	opts := SynthOptions{
+		TestApp:      "beacon",
+		TfWorkingDir: "tf/beacon",
+		Executor:     &PnpmExecutor,
+		EntryPath:    "bundles/beacon/main.ts",
+		StackName:    "beacon-stack",
+		RepoRoot:     "../..",
+	}.withDefaults()
+	assert.Equal(t, &PnpmExecutor, opts.Executor)
+	assert.Equal(t, "bundles/beacon/main.ts", opts.EntryPath)
+	assert.Equal(t, "beacon-stack", opts.StackName)
+
+	srcImportPath, err := opts.srcImportPath()
+	require.NoError(t, err)
+	assert.Equal(t, "../../../../src", srcImportPath)
+}
+
+func TestSynthAppWithOptionsE_Errors(t *testing.T) {
+	_, err := SynthAppWithOptionsE(t, SynthOptions{TestApp: "missing"})
+	assert.ErrorContains(t, err, "TestApp and TfWorkingDir are required")
+
+	_, err = SynthAppWithOptionsE(t, SynthOptions{TestApp: "missing", TfWorkingDir: t.TempDir()})
+	assert.ErrorContains(t, err, "failed to read apps/missing.ts")
+}
+
+func TestSynthOptions_CopySynthOutput(t *testing.T) {
+	outputDir := t.TempDir()
+	writeTestFile(t, filepath.Join(outputDir, ManifestFileName), "{}")
+	writeTestFile(t, filepath.Join(outputDir, "stacks", "app", "cdk.tf.json"), `{"app": true}`)
+	writeTestFile(t, filepath.Join(outputDir, "stacks", "certificate", "cdk.tf.json"), `{"certificate": true}`)
+
+	t.Run("stack", func(t *testing.T) {
+		opts := SynthOptions{TestApp: "app", TfWorkingDir: t.TempDir()}.withDefaults()
+		require.NoError(t, opts.copySynthOutput(outputDir))
+		assert.FileExists(t, filepath.Join(opts.TfWorkingDir, "cdk.tf.json"))
+		assert.NoFileExists(t, filepath.Join(opts.TfWorkingDir, ManifestFileName))
+	})
+	t.Run("all stacks", func(t *testing.T) {
+		opts := SynthOptions{TestApp: "app", TfWorkingDir: t.TempDir(), AllStacks: true}.withDefaults()
+		require.NoError(t, opts.copySynthOutput(outputDir))
+		assert.FileExists(t, filepath.Join(opts.TfWorkingDir, ManifestFileName))
+		assert.FileExists(t, filepath.Join(opts.TfWorkingDir, "stacks", "certificate", "cdk.tf.json"))
+	})
+	t.Run("missing stack", func(t *testing.T) {
+		opts := SynthOptions{TestApp: "app", TfWorkingDir: t.TempDir(), StackName: "missing"}.withDefaults()
+		assert.EqualError(t, opts.copySynthOutput(outputDir), "stack 'missing' not found in synth output of 'app'")
+	})
+}
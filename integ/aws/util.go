@@ -2,31 +2,28 @@ package aws
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
 	"os"
-	"path/filepath"
-	"strings"
 	"testing"
 	"text/template"
 
-	"github.com/environment-toolkit/go-synth"
-	"github.com/environment-toolkit/go-synth/executors"
 	"github.com/environment-toolkit/go-synth/models"
+	"github.com/envtio/base/integ/redact"
+	"github.com/envtio/base/integ/tfoutput"
 	"github.com/google/go-cmp/cmp"
 	loggers "github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	tfjson "github.com/hashicorp/terraform-json"
-	"github.com/spf13/afero"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-var terratestLogger = loggers.Default
+// terratestLogger masks sensitive output values
+var terratestLogger = redact.Logger
 
 const (
 	// path from integ/aws/* to repo root
@@ -35,6 +32,10 @@ const (
 	relPath = "./envtio/base"
 )
 
+// DefaultLockTimeout is the time Terraform commands of a deployed working dir wait for the state lock,
+// i.e. a destroy by the cleanup watchdog or signal handler waits for a running apply or plan
+const DefaultLockTimeout = "10m"
+
 var (
 	// Directories to skip when copying files to the synth app fs
 	defaultCopyOptions = models.CopyOptions{
@@ -55,51 +56,14 @@ var (
 	}
 )
 
-// Synth app relative to the integration namespace
+// Synth app relative to the integration namespace, see SynthAppWithOptions to synth apps with another layout or executor
 func SynthApp(t *testing.T, testApp, tfWorkingDir string, env map[string]string, additionalAppDirs ...string) {
-	zapLogger := ForwardingLogger(t, terratestLogger)
-	ctx := context.Background()
-	// path from integ/aws/*/apps/*.ts to repo root src
-	mainPathToSrc := filepath.Join("..", repoRoot, "src")
-	if _, err := os.Stat(filepath.Join(repoRoot, "lib")); err != nil {
-		t.Fatal("No lib folder, run pnpm compile before go test")
-	}
-	mainTsFile := filepath.Join("apps", testApp+".ts")
-	mainTsBytes, err := os.ReadFile(mainTsFile)
-	if err != nil {
-		t.Fatal("Failed to read" + mainTsFile)
-	}
-
-	// load dependencies to Synth app
-	var synthDependencies map[string]string
-	LoadSynthDependencies(t, tfWorkingDir, &synthDependencies)
-	if synthDependencies == nil {
-		synthDependencies = make(map[string]string)
-	}
-	synthDependencies["@envtio/base"] = relPath
-
-	thisFs := afero.NewOsFs()
-	app := synth.NewApp(executors.NewBunExecutor, zapLogger)
-	app.Configure(ctx, models.AppConfig{
-		EnvVars: env,
-		// copy additionalDirs and @envtio/base to synth App fs
-		PreSetupFn: func(e models.Executor) error {
-			for _, dirName := range additionalAppDirs {
-				relDir := filepath.Join("apps", dirName)
-				if err := e.CopyFrom(ctx, thisFs, relDir, dirName, defaultCopyOptions); err != nil {
-					return err
-				}
-			}
-			return e.CopyFrom(ctx, thisFs, repoRoot, relPath, defaultCopyOptions)
-		},
-		Dependencies: synthDependencies,
+	SynthAppWithOptions(t, SynthOptions{
+		TestApp:           testApp,
+		TfWorkingDir:      tfWorkingDir,
+		Env:               env,
+		AdditionalAppDirs: additionalAppDirs,
 	})
-	// replace the path to src with relative package "@envtio/base"
-	mainTs := strings.ReplaceAll(string(mainTsBytes), mainPathToSrc, "@envtio/base")
-	err = app.Eval(ctx, thisFs, mainTs, "cdktf.out/stacks/"+testApp, tfWorkingDir)
-	if err != nil {
-		t.Fatal("Failed to synth app", err)
-	}
 }
 
 // SaveSynthDependencies serializes and saves map of dependencies at test time to the given path.
@@ -126,9 +90,13 @@ func formatSynthDependenciesPath(testFolder string) string {
 func DeployUsingTerraform(t *testing.T, workingDir string, additionalRetryableErrors map[string]string) {
 	// Construct the terraform options with default retryable errors to handle the most common retryable errors in
 	// terraform testing.
+	// the state is locked, so test stages never run concurrently with a destroy of the cleanup registry
 	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
 		TerraformDir:    workingDir,
 		TerraformBinary: "tofu",
+		Lock:            true,
+		LockTimeout:     DefaultLockTimeout,
+		Logger:          redact.Logger, // tofu output includes secrets of the test app environment
 	})
 
 	for k, v := range additionalRetryableErrors {
@@ -141,15 +109,53 @@ func DeployUsingTerraform(t *testing.T, workingDir string, additionalRetryableEr
 }
 
 func UndeployUsingTerraform(t *testing.T, workingDir string) {
-	terraformOptions := test_structure.LoadTerraformOptions(t, workingDir)
+	terraformOptions := LoadTerraformOptions(t, workingDir)
 	terraform.Destroy(t, terraformOptions)
 }
 
-// LoadOutputAttribute loads the attribute of a output key from Terraform outputs and ensures it is not empty.
+// LoadTerraformOptions loads the Terraform Options saved by DeployUsingTerraform with redact.Logger,
+// the logger is not serialized with the options.
+func LoadTerraformOptions(t *testing.T, workingDir string) *terraform.Options {
+	terraformOptions := test_structure.LoadTerraformOptions(t, workingDir)
+	terraformOptions.Logger = redact.Logger
+	return terraformOptions
+}
+
+// LoadOutputAttribute loads the attribute of a output key from the cached Terraform outputs and ensures it is not empty.
+//
+// Fails the test if the output is sensitive, use RevealSensitiveOutputAttribute to access sensitive outputs.
 func LoadOutputAttribute(t *testing.T, terraformOptions *terraform.Options, key, attribute string) string {
-	outputs := terraform.OutputMap(t, terraformOptions, key)
-	value := outputs[attribute]
+	output := loadOutput(t, terraformOptions, key)
+	require.False(t, output.Sensitive, "Output %s is sensitive, use RevealSensitiveOutputAttribute", key)
+	return outputAttribute(t, output, key, attribute)
+}
+
+// RevealSensitiveOutputAttribute loads the attribute of a sensitive output key from the cached Terraform outputs and ensures it is not empty.
+//
+// The revealed value is masked in logs.
+func RevealSensitiveOutputAttribute(t *testing.T, terraformOptions *terraform.Options, key, attribute string) string {
+	output := loadOutput(t, terraformOptions, key)
+	return outputAttribute(t, output, key, attribute)
+}
+
+// loadOutput returns an output of the cached snapshot, see tfoutput.LoadE
+func loadOutput(t *testing.T, terraformOptions *terraform.Options, key string) tfoutput.Output {
+	snapshot, err := tfoutput.LoadE(t, terraformOptions)
+	require.NoError(t, err)
+	output, ok := snapshot.Outputs[key]
+	require.True(t, ok, "Output %s not found", key)
+	return output
+}
+
+func outputAttribute(t *testing.T, output tfoutput.Output, key, attribute string) string {
+	attributes, ok := output.Value.(map[string]any)
+	require.True(t, ok, "Output %s should be a map", key)
+	var value string
+	if v, ok := attributes[attribute]; ok && v != nil {
+		value = fmt.Sprintf("%v", v)
+	}
 	require.NotEmpty(t, value, fmt.Sprintf("Output %s.%s should not be empty", key, attribute))
+	terratestLogger.Logf(t, "Output %s.%s: %s", key, attribute, value)
 	return value
 }
 
@@ -208,7 +214,8 @@ func (fc *ForwardingCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapc
 }
 
 func (fc *ForwardingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
-	fc.targetLogger.Logf(fc.t, "[%s] %s", entry.Level, entry.Message)
+	// synth executor output may include sensitive values
+	fc.targetLogger.Logf(fc.t, "[%s] %s", entry.Level, redact.String(entry.Message))
 	return nil
 }
 
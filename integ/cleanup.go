@@ -0,0 +1,336 @@
+package integ
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	util "github.com/envtio/base/integ/aws"
+	"github.com/envtio/base/integ/plan"
+	"github.com/envtio/base/integ/redact"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// CleanupMarkerFileName is the test data file marking a working dir deployed by a run that did not clean up yet
+	CleanupMarkerFileName = "NeedsCleanup.json"
+	// MinWatchdogMargin is the minimum time before the test deadline (`go test -timeout`) the watchdog destroys the registered working dirs
+	MinWatchdogMargin = 5 * time.Minute
+	// WatchdogMarginDivisor reserves a part of the remaining test budget for the destroy by the watchdog,
+	// i.e. 10m of `-timeout 30m` and 20m of `-timeout 60m` for CloudFront distributions
+	WatchdogMarginDivisor = 3
+	// WatchdogMarginEnvVar overrides the margin derived from the test budget, i.e. `CLEANUP_WATCHDOG_MARGIN=10m`
+	WatchdogMarginEnvVar = "CLEANUP_WATCHDOG_MARGIN"
+	// CleanupPendingEnvVar enables TestCleanupPending, which destroys the pending cleanups of all namespaces, see `make cleanup-pending`
+	CleanupPendingEnvVar = "CLEANUP_PENDING"
+)
+
+// CleanupMarker records a deployed working dir until it is destroyed, so a later run can finish the cleanup, see CleanupPending.
+type CleanupMarker struct {
+	Test     string    `json:"test"`     // Name of the test deploying the working dir
+	Dir      string    `json:"dir"`      // Terraform working dir
+	Deployed time.Time `json:"deployed"` // Time of the deploy
+}
+
+// formatCleanupMarkerPath returns the path of the cleanup marker in the test data of the working dir
+func formatCleanupMarkerPath(tfWorkingDir string) string {
+	return test_structure.FormatTestDataPath(tfWorkingDir, CleanupMarkerFileName)
+}
+
+// WriteCleanupMarkerE marks the working dir as deployed by the test until RemoveCleanupMarkerE.
+func WriteCleanupMarkerE(tfWorkingDir, testName string) error {
+	data, err := json.MarshalIndent(CleanupMarker{Test: testName, Dir: tfWorkingDir, Deployed: time.Now()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := formatCleanupMarkerPath(tfWorkingDir)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// RemoveCleanupMarkerE removes the cleanup marker of the working dir, if any.
+func RemoveCleanupMarkerE(tfWorkingDir string) error {
+	if err := os.Remove(formatCleanupMarkerPath(tfWorkingDir)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// HasCleanupMarker returns true if the working dir was deployed and not destroyed yet.
+func HasCleanupMarker(tfWorkingDir string) bool {
+	_, err := os.Stat(formatCleanupMarkerPath(tfWorkingDir))
+	return err == nil
+}
+
+// PendingCleanupsE returns the working dirs below root with a cleanup marker, sorted by path.
+func PendingCleanupsE(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".terraform" || d.Name() == "node_modules" {
+			return filepath.SkipDir
+		}
+		if HasCleanupMarker(path) {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	sort.Strings(dirs)
+	return dirs, err
+}
+
+// CleanupPending destroys the working dirs below root left deployed by interrupted or timed out runs, i.e. `tf`.
+// Fails the test if any working dir could not be destroyed, its marker is kept for the next attempt.
+func CleanupPending(t *testing.T, root string) {
+	dirs, err := PendingCleanupsE(root)
+	require.NoError(t, err)
+	if len(dirs) == 0 {
+		terratestLogger.Logf(t, "No pending cleanups in %s", root)
+		return
+	}
+	var combinedErr error
+	for _, dir := range dirs {
+		terratestLogger.Logf(t, "Finishing pending cleanup of %s", dir)
+		if err := cleanups.destroyE(t, dir); err != nil {
+			combinedErr = multierror.Append(combinedErr, fmt.Errorf("failed to destroy '%s': %v", dir, err))
+		}
+	}
+	require.NoError(t, combinedErr)
+}
+
+// watchdogMargin returns $CLEANUP_WATCHDOG_MARGIN or the share of the remaining test budget (see WatchdogMarginDivisor),
+// at least MinWatchdogMargin
+func watchdogMargin(remaining time.Duration) (time.Duration, error) {
+	margin := os.Getenv(WatchdogMarginEnvVar)
+	if margin == "" {
+		return max(MinWatchdogMargin, remaining/WatchdogMarginDivisor), nil
+	}
+	d, err := time.ParseDuration(margin)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s '%s': %v", WatchdogMarginEnvVar, margin, err)
+	}
+	return d, nil
+}
+
+// cleanupRegistry destroys the registered working dirs of running tests on SIGINT/SIGTERM and, by a watchdog per
+// test, shortly before `go test -timeout` ends the test binary without running deferred cleanups.
+type cleanupRegistry struct {
+	mu        sync.Mutex
+	dirs      map[string]*testing.T  // Registered working dirs and the test owning them
+	locks     map[string]*sync.Mutex // Serializes destroys per working dir
+	installed bool                   // Signal handlers installed
+	exit      func(code int)         // Exits the test binary after an interrupt, os.Exit
+}
+
+// cleanups is the registry of the integ.Run working dirs
+var cleanups = &cleanupRegistry{
+	dirs:  map[string]*testing.T{},
+	locks: map[string]*sync.Mutex{},
+	exit:  os.Exit,
+}
+
+// RegisterCleanup destroys the working dir of the test on SIGINT/SIGTERM or shortly before the test deadline,
+// see WatchdogMarginDivisor. The working dir is unregistered when the test completes.
+func RegisterCleanup(t *testing.T, tfWorkingDir string) {
+	cleanups.register(t, tfWorkingDir)
+}
+
+func (r *cleanupRegistry) register(t *testing.T, tfWorkingDir string) {
+	r.mu.Lock()
+	r.dirs[tfWorkingDir] = t
+	if !r.installed {
+		r.installed = true
+		signals := make(chan os.Signal, 2)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go r.handleSignals(signals)
+	}
+	r.mu.Unlock()
+
+	watchdog := r.startWatchdog(t, tfWorkingDir)
+	t.Cleanup(func() {
+		if watchdog != nil {
+			watchdog.Stop()
+		}
+		r.mu.Lock()
+		delete(r.dirs, tfWorkingDir)
+		r.mu.Unlock()
+	})
+}
+
+// startWatchdog destroys the working dir the watchdog margin before the test deadline, nil without a deadline
+func (r *cleanupRegistry) startWatchdog(t *testing.T, tfWorkingDir string) *time.Timer {
+	deadline, ok := t.Deadline()
+	if !ok {
+		return nil
+	}
+	remaining := time.Until(deadline)
+	margin, err := watchdogMargin(remaining)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remaining <= margin {
+		terratestLogger.Logf(t, "Cleanup watchdog of %s disabled, the test deadline in %s is within the %s margin", tfWorkingDir, remaining.Round(time.Second), margin)
+		return nil
+	}
+	return time.AfterFunc(remaining-margin, func() {
+		terratestLogger.Logf(t, "Test deadline in %s, destroying %s", margin, tfWorkingDir)
+		if err := r.destroyE(t, tfWorkingDir); err != nil {
+			terratestLogger.Logf(t, "Watchdog failed to destroy %s: %v", tfWorkingDir, err)
+		}
+	})
+}
+
+// handleSignals destroys all registered working dirs on the first signal and exits, a second signal exits right away
+func (r *cleanupRegistry) handleSignals(signals chan os.Signal) {
+	sig := <-signals
+	fmt.Fprintf(os.Stderr, "Received %s, destroying the deployed test apps, interrupt again to exit without cleanup\n", sig)
+	go func() {
+		<-signals
+		fmt.Fprintln(os.Stderr, "Interrupted again, exiting without cleanup, see CleanupPending")
+		r.exit(1)
+	}()
+	if err := r.destroyAllE(); err != nil {
+		fmt.Fprintf(os.Stderr, "Cleanup after %s failed: %v\n", sig, err)
+	}
+	r.exit(1)
+}
+
+// destroyAllE destroys all registered working dirs in parallel
+func (r *cleanupRegistry) destroyAllE() error {
+	r.mu.Lock()
+	dirs := make(map[string]*testing.T, len(r.dirs))
+	for dir, t := range r.dirs {
+		dirs[dir] = t
+	}
+	r.mu.Unlock()
+
+	var wg sync.WaitGroup
+	var errMu sync.Mutex
+	var combinedErr error
+	for dir, t := range dirs {
+		wg.Add(1)
+		go func(dir string, t *testing.T) {
+			defer wg.Done()
+			if err := r.destroyE(t, dir); err != nil {
+				errMu.Lock()
+				combinedErr = multierror.Append(combinedErr, fmt.Errorf("failed to destroy '%s': %v", dir, err))
+				errMu.Unlock()
+			}
+		}(dir, t)
+	}
+	wg.Wait()
+	return combinedErr
+}
+
+// lock returns the destroy lock of the working dir
+func (r *cleanupRegistry) lock(tfWorkingDir string) *sync.Mutex {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.locks[tfWorkingDir]
+	if !ok {
+		l = &sync.Mutex{}
+		r.locks[tfWorkingDir] = l
+	}
+	return l
+}
+
+// destroyE destroys the working dir with the Terraform Options saved by the deploy stage and removes its cleanup
+// marker. The stacks of a working dir synthesized by util.SynthStacks are destroyed in reverse dependency order.
+// Destroys of the same working dir by the cleanup stage, the watchdog and the signal handler never overlap.
+// Skips working dirs which were not deployed. Safe to call outside of the test goroutine.
+func (r *cleanupRegistry) destroyE(t *testing.T, tfWorkingDir string) error {
+	l := r.lock(tfWorkingDir)
+	l.Lock()
+	defer l.Unlock()
+
+	dirs := []string{tfWorkingDir}
+	stacks, err := util.LoadStacksE(tfWorkingDir)
+	if err == nil {
+		dirs = dirs[:0]
+		for i := len(stacks.Order) - 1; i >= 0; i-- {
+			dirs = append(dirs, stacks.WorkingDir(stacks.Order[i]))
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	var combinedErr error
+	for _, dir := range dirs {
+		if err := destroyDirE(t, dir); err != nil {
+			combinedErr = multierror.Append(combinedErr, err)
+		}
+	}
+	if combinedErr != nil {
+		return combinedErr
+	}
+	return RemoveCleanupMarkerE(tfWorkingDir)
+}
+
+// destroyDirE destroys a working dir with the Terraform Options saved by the deploy stage, if deployed
+func destroyDirE(t *testing.T, tfWorkingDir string) error {
+	terraformOptions, err := loadDestroyOptionsE(tfWorkingDir)
+	if err != nil {
+		return err
+	}
+	if terraformOptions == nil {
+		terratestLogger.Logf(t, "Skipping destroy of %s, it was not deployed", tfWorkingDir)
+		return nil
+	}
+	// resources removed from state by an interrupted import round-trip
+	if restored, err := plan.RestoreImportStateE(t, terraformOptions); err != nil {
+		return err
+	} else if restored {
+		terratestLogger.Logf(t, "Restored the state of %s backed up by the import round-trip", tfWorkingDir)
+	}
+	if _, err := terraform.DestroyE(t, terraformOptions); err != nil {
+		return fmt.Errorf("failed to destroy '%s': %v", tfWorkingDir, err)
+	}
+	return nil
+}
+
+// loadDestroyOptionsE returns the Terraform Options saved by the deploy stage of the working dir, nil if not deployed.
+// The saved TerraformDir is relative to the namespace the test ran in, it is replaced by the working dir found by the
+// caller, i.e. `aws/compute/tf/fifo-queue` found by TestCleanupPending instead of `tf/fifo-queue`.
+func loadDestroyOptionsE(tfWorkingDir string) (*terraform.Options, error) {
+	optionsPath := test_structure.FormatTestDataPath(tfWorkingDir, "TerraformOptions.json")
+	data, err := os.ReadFile(optionsPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var terraformOptions terraform.Options
+	if err := json.Unmarshal(data, &terraformOptions); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", optionsPath, err)
+	}
+	terraformOptions.TerraformDir = tfWorkingDir
+	// the logger is not serialized with the options
+	terraformOptions.Logger = redact.Logger
+	// options saved before locking was enabled must not destroy concurrently with a running stage
+	terraformOptions.Lock = true
+	if terraformOptions.LockTimeout == "" {
+		terraformOptions.LockTimeout = util.DefaultLockTimeout
+	}
+	return &terraformOptions, nil
+}
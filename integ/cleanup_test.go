@@ -0,0 +1,197 @@
+package integ
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	util "github.com/envtio/base/integ/aws"
+	"github.com/envtio/base/integ/plan"
+	"github.com/envtio/base/integ/redact"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRegistry returns a registry without signal handlers recording the exit code
+func newTestRegistry(exitCodes chan int) *cleanupRegistry {
+	return &cleanupRegistry{
+		dirs:      map[string]*testing.T{},
+		locks:     map[string]*sync.Mutex{},
+		installed: true,
+		exit:      func(code int) { exitCodes <- code },
+	}
+}
+
+func TestCleanupMarker(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "fifo-queue")
+	assert.False(t, HasCleanupMarker(dir))
+
+	require.NoError(t, WriteCleanupMarkerE(dir, "TestFifoQueue"))
+	assert.True(t, HasCleanupMarker(dir))
+	assert.FileExists(t, filepath.Join(dir, ".test-data", CleanupMarkerFileName))
+
+	require.NoError(t, RemoveCleanupMarkerE(dir))
+	assert.False(t, HasCleanupMarker(dir))
+	// removing a missing marker is not an error
+	require.NoError(t, RemoveCleanupMarkerE(dir))
+}
+
+func TestPendingCleanupsE(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"fifo-queue", "app/app-arm64", "app/app-x86_64/.terraform/modules/queue"} {
+		require.NoError(t, WriteCleanupMarkerE(filepath.Join(root, dir), "TestApp"))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "dlq-queue"), 0755))
+
+	dirs, err := PendingCleanupsE(root)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(root, "app/app-arm64"), filepath.Join(root, "fifo-queue")}, dirs)
+
+	dirs, err = PendingCleanupsE(filepath.Join(root, "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, dirs)
+}
+
+// Destroy the apps left deployed by interrupted or timed out runs of all namespaces, see `make cleanup-pending`
+func TestCleanupPending(t *testing.T) {
+	if os.Getenv(CleanupPendingEnvVar) != "true" {
+		t.Skipf("%s is not set", CleanupPendingEnvVar)
+	}
+	roots, err := filepath.Glob(filepath.Join("aws", "*", "tf"))
+	require.NoError(t, err)
+	for _, root := range roots {
+		// a namespace failing to destroy does not stop the others
+		t.Run(root, func(t *testing.T) {
+			CleanupPending(t, root)
+		})
+	}
+}
+
+func TestWatchdogMargin(t *testing.T) {
+	testCases := []struct {
+		name      string
+		value     string
+		remaining time.Duration
+		expected  time.Duration
+		err       string
+	}{
+		{name: "minimum", remaining: 12 * time.Minute, expected: MinWatchdogMargin},
+		{name: "derived", remaining: 60 * time.Minute, expected: 20 * time.Minute},
+		{name: "override", value: "10m", remaining: 60 * time.Minute, expected: 10 * time.Minute},
+		{name: "invalid", value: "ten minutes", err: "invalid CLEANUP_WATCHDOG_MARGIN 'ten minutes'"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(WatchdogMarginEnvVar, tc.value)
+			margin, err := watchdogMargin(tc.remaining)
+			if tc.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, margin)
+		})
+	}
+}
+
+func TestCleanupRegistry_Register(t *testing.T) {
+	r := newTestRegistry(make(chan int, 1))
+	t.Run("test", func(t *testing.T) {
+		r.register(t, "tf/fifo-queue")
+		assert.Contains(t, r.dirs, "tf/fifo-queue")
+	})
+	// unregistered once the test completes
+	assert.Empty(t, r.dirs)
+}
+
+func TestCleanupRegistry_DestroyNotDeployed(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "fifo-queue")
+	require.NoError(t, WriteCleanupMarkerE(dir, "TestFifoQueue"))
+
+	// without saved Terraform Options there is nothing to destroy
+	require.NoError(t, newTestRegistry(make(chan int, 1)).destroyE(t, dir))
+	assert.False(t, HasCleanupMarker(dir))
+}
+
+func TestCleanupRegistry_DestroyStacks(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "multi-stack")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, util.ManifestFileName), []byte(`{
+		"version": "0.20.9",
+		"stacks": {
+			"network": {"name": "network", "workingDirectory": "stacks/network"},
+			"app": {"name": "app", "workingDirectory": "stacks/app", "dependencies": ["network"]}
+		}
+	}`), 0644))
+	require.NoError(t, WriteCleanupMarkerE(dir, "TestMultiStack"))
+
+	// options of a deployed stack which can not be parsed fail the destroy and keep the marker
+	optionsPath := filepath.Join(dir, "stacks", "network", ".test-data", "TerraformOptions.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(optionsPath), 0755))
+	require.NoError(t, os.WriteFile(optionsPath, []byte("not json"), 0644))
+	err := newTestRegistry(make(chan int, 1)).destroyE(t, dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), filepath.Join("stacks", "network", ".test-data", "TerraformOptions.json"))
+	assert.True(t, HasCleanupMarker(dir))
+
+	// stacks which were not deployed are skipped
+	require.NoError(t, os.Remove(optionsPath))
+	require.NoError(t, newTestRegistry(make(chan int, 1)).destroyE(t, dir))
+	assert.False(t, HasCleanupMarker(dir))
+}
+
+func TestLoadDestroyOptionsE(t *testing.T) {
+	root := t.TempDir()
+	// options saved by a test of the compute namespace, relative to its working dir
+	dir := filepath.Join("aws", "compute", "tf", "fifo-queue")
+	optionsPath := filepath.Join(root, dir, ".test-data", "TerraformOptions.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(optionsPath), 0755))
+	require.NoError(t, os.WriteFile(optionsPath, []byte(`{"TerraformDir": "tf/fifo-queue", "TerraformBinary": "tofu"}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, dir, ".test-data", plan.ImportStateBackupFileName), []byte("{}"), 0600))
+
+	// read from the integ dir like TestCleanupPending
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(root))
+	t.Cleanup(func() { require.NoError(t, os.Chdir(wd)) })
+
+	terraformOptions, err := loadDestroyOptionsE(dir)
+	require.NoError(t, err)
+	require.NotNil(t, terraformOptions)
+	assert.Equal(t, dir, terraformOptions.TerraformDir)
+	assert.Equal(t, "tofu", terraformOptions.TerraformBinary)
+	assert.True(t, terraformOptions.Lock)
+	assert.Equal(t, util.DefaultLockTimeout, terraformOptions.LockTimeout)
+	assert.Same(t, redact.Logger, terraformOptions.Logger)
+	// the import state backup is found relative to the working dir
+	assert.FileExists(t, test_structure.FormatTestDataPath(terraformOptions.TerraformDir, plan.ImportStateBackupFileName))
+
+	terraformOptions, err = loadDestroyOptionsE(filepath.Join("aws", "compute", "tf", "dlq-queue"))
+	require.NoError(t, err)
+	assert.Nil(t, terraformOptions)
+}
+
+func TestCleanupRegistry_HandleSignals(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "fifo-queue")
+	require.NoError(t, WriteCleanupMarkerE(dir, "TestFifoQueue"))
+	exitCodes := make(chan int, 2)
+	r := newTestRegistry(exitCodes)
+	r.dirs[dir] = t
+
+	signals := make(chan os.Signal, 2)
+	go r.handleSignals(signals)
+	signals <- syscall.SIGTERM
+
+	select {
+	case code := <-exitCodes:
+		assert.Equal(t, 1, code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("signal handler did not exit")
+	}
+	assert.False(t, HasCleanupMarker(dir))
+}
@@ -36,21 +36,25 @@ help: ## Print out every target with a description
 	SKIP_cleanup_terraform=true make $*
 .PHONY: %-no-cleanup
 
-## %-synth-only:              Skip deploy, validate, and cleanup steps (i.e. foo-synth-only)
+## %-synth-only:              Skip deploy, checks, validate, Day-2 and cleanup steps (i.e. foo-synth-only)
 %-synth-only:
-	SKIP_deploy_terraform=true SKIP_validate=true SKIP_cleanup_terraform=true make $*
+	SKIP_day2=true SKIP_deploy_terraform=true SKIP_check_idempotent=true SKIP_validate=true SKIP_check_drift=true SKIP_import_roundtrip=true SKIP_plan_upgrade=true SKIP_apply_upgrade=true SKIP_validate_upgrade=true SKIP_cleanup_terraform=true make $*
 .PHONY: %-synth-only
 
-## %-validate-only:           Skip synth and cleanup steps (i.e. foo-validate-only)
+## %-validate-only:           Skip synth, import round-trip, upgrade, Day-2 and cleanup steps (i.e. foo-validate-only)
 %-validate-only:
-	SKIP_synth_app=true SKIP_cleanup_terraform=true make $*
+	SKIP_day2=true SKIP_synth_app=true SKIP_import_roundtrip=true SKIP_upgrade_app=true SKIP_plan_upgrade=true SKIP_apply_upgrade=true SKIP_validate_upgrade=true SKIP_cleanup_terraform=true make $*
 .PHONY: %-validate-only
 
-## %-cleanup-only:            Skip synth, deploy, and validate steps (i.e. foo-cleanup-only)
+## %-cleanup-only:            Skip synth, deploy, checks, validate and Day-2 steps (i.e. foo-cleanup-only)
 %-cleanup-only:
-	SKIP_synth_app=true SKIP_deploy_terraform=true SKIP_validate=true make $*
+	SKIP_day2=true SKIP_synth_app=true SKIP_deploy_terraform=true SKIP_check_idempotent=true SKIP_validate=true SKIP_check_drift=true SKIP_import_roundtrip=true SKIP_upgrade_app=true SKIP_plan_upgrade=true SKIP_apply_upgrade=true SKIP_validate_upgrade=true make $*
 .PHONY: %-cleanup-only
 
+cleanup-pending: ## Destroy apps left deployed by interrupted or timed out runs of all namespaces, see integ/Makefile
+	$(MAKE) -C ../.. cleanup-pending
+.PHONY: cleanup-pending
+
 clean: ## clean up temporary files (tf/*, apps/cdktf.out, /tmp/go-synth-*)
 	rm -rf tf/*
 	rm -rf apps/cdktf.out
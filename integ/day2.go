@@ -0,0 +1,74 @@
+package integ
+
+import (
+	"testing"
+
+	util "github.com/envtio/base/integ/aws"
+	"github.com/envtio/base/integ/plan"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+)
+
+// SkipDay2EnvVar skips the stages of all Day-2 steps if set, their stage names depend on the step names
+const SkipDay2EnvVar = "SKIP_day2"
+
+// Day2Step changes the env vars or template variables of the deployed app, re-synths it in the stage `<Name>_app`
+// and optionally plans it against the deployed app (`plan_<Name>`), applies it (`apply_<Name>`) and validates the
+// result (`validate_<Name>`). Env and Variables carry over to the following steps.
+//
+//	integ.Day2Step{
+//		Name:     "add-dlq",
+//		Env:      map[string]string{"DLQ_ENABLED": "true"},
+//		Expect:   []plan.Expectation{plan.ExpectOnly(plan.ChangeSet{Create: []string{"aws_sqs_queue.*"}})},
+//		Apply:    true,
+//		Validate: validateDlq,
+//	}
+type Day2Step struct {
+	Name      string             // Name of the step, i.e. `rename`
+	Env       map[string]string  // Env vars changed for this and all following steps
+	Variables util.Variables     // Template variables changed for this and all following steps, see util.RunMatrix
+	Expect    []plan.Expectation // Expectations of the plan against the deployed app, no plan if empty
+	Apply     bool               // Apply the re-synthesized app before Validate
+	Validate  Validator          // Validates the step, i.e. the applied app or a plan against the deployed app
+}
+
+// SynthStage returns the name of the stage re-synthesizing the app
+func (s Day2Step) SynthStage() string {
+	return s.Name + "_app"
+}
+
+// PlanStage returns the name of the stage checking the plan against Expect
+func (s Day2Step) PlanStage() string {
+	return "plan_" + s.Name
+}
+
+// ApplyStage returns the name of the stage applying the re-synthesized app
+func (s Day2Step) ApplyStage() string {
+	return "apply_" + s.Name
+}
+
+// ValidateStage returns the name of the stage validating the step
+func (s Day2Step) ValidateStage() string {
+	return "validate_" + s.Name
+}
+
+// runDay2Step runs the stages of a Day-2 step against the deployed app
+func (s Scenario) runDay2Step(t *testing.T, step Day2Step, tfWorkingDir, awsRegion string, env map[string]string, vars util.Variables) {
+	test_structure.RunTestStage(t, step.SynthStage(), func() {
+		s.synth(t, tfWorkingDir, env, vars, nil)
+	})
+	if len(step.Expect) > 0 {
+		test_structure.RunTestStage(t, step.PlanStage(), func() {
+			plan.Replan(t, tfWorkingDir, step.Expect...)
+		})
+	}
+	if step.Apply {
+		test_structure.RunTestStage(t, step.ApplyStage(), func() {
+			util.DeployUsingTerraform(t, tfWorkingDir, s.RetryableErrors)
+		})
+	}
+	if step.Validate != nil {
+		test_structure.RunTestStage(t, step.ValidateStage(), func() {
+			step.Validate(t, tfWorkingDir, awsRegion)
+		})
+	}
+}
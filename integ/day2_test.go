@@ -0,0 +1,16 @@
+package integ
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDay2Step_Stages(t *testing.T) {
+	step := Day2Step{Name: "rename"}
+	// stage names of the former run*IntegrationTestWithRename helpers
+	assert.Equal(t, "rename_app", step.SynthStage())
+	assert.Equal(t, "plan_rename", step.PlanStage())
+	assert.Equal(t, "apply_rename", step.ApplyStage())
+	assert.Equal(t, "validate_rename", step.ValidateStage())
+}
@@ -0,0 +1,171 @@
+package integ
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+const (
+	// DefaultPollInterval is the initial interval between fetches
+	DefaultPollInterval = 1 * time.Second
+	// DefaultPollMaxInterval is the maximum interval between fetches after backoff
+	DefaultPollMaxInterval = 30 * time.Second
+	// DefaultPollTimeout is used when neither Timeout nor a test deadline is set
+	DefaultPollTimeout = 10 * time.Minute
+	// DefaultDeadlineMargin is the time reserved before the test deadline (i.e. for cleanup stages)
+	DefaultDeadlineMargin = 1 * time.Minute
+	// DefaultPollJitter is the fraction of random jitter applied to intervals
+	DefaultPollJitter = 0.2
+)
+
+// PollOptions configures AssertEventually and AssertConsistently.
+type PollOptions struct {
+	Timeout        time.Duration // AssertEventually: maximum time to wait, capped by the test deadline
+	Duration       time.Duration // AssertConsistently: window during which the assertions must hold, capped by the test deadline
+	Interval       time.Duration // Initial interval between fetches, defaults to DefaultPollInterval
+	MaxInterval    time.Duration // AssertEventually: maximum interval after exponential backoff, defaults to DefaultPollMaxInterval
+	Jitter         float64       // Fraction of random jitter applied to every interval, defaults to DefaultPollJitter
+	DeadlineMargin time.Duration // Time reserved before t.Deadline(), defaults to DefaultDeadlineMargin
+}
+
+// FetchFunc fetches the input for assertions, i.e. by calling an AWS API.
+type FetchFunc func() (any, error)
+
+// AssertEventually re-runs fetch with exponential backoff and jitter until all assertions pass.
+// Fails the test if the assertions do not pass before the timeout or test deadline.
+//
+// The results of the last attempt are recorded in the test reports (see ReporterFor).
+func AssertEventually(t *testing.T, fetch FetchFunc, assertions []Assertion, opts PollOptions) {
+	results, err := assertEventually(t, fetch, assertions, opts)
+	ReporterFor(t).Record("", results...)
+	if err != nil {
+		t.Errorf("failed eventual assertions: %v", err)
+	}
+}
+
+// AssertEventuallyE re-runs fetch with exponential backoff and jitter until all assertions pass,
+// returns the last error if the assertions do not pass before the timeout or test deadline.
+func AssertEventuallyE(t *testing.T, fetch FetchFunc, assertions []Assertion, opts PollOptions) error {
+	_, err := assertEventually(t, fetch, assertions, opts)
+	return err
+}
+
+// assertEventually returns the results of the last attempt of AssertEventuallyE, nil if the fetch failed
+func assertEventually(t *testing.T, fetch FetchFunc, assertions []Assertion, opts PollOptions) ([]AssertionResult, error) {
+	opts = opts.withDefaults()
+	start := time.Now()
+	deadline := opts.deadline(t, start, opts.Timeout)
+	interval := opts.Interval
+	for attempt := 1; ; attempt++ {
+		results, err := fetchAndEvaluate(fetch, assertions)
+		if err == nil {
+			terratestLogger.Logf(t, "Assertions passed after %d attempt(s) in %s", attempt, time.Since(start).Round(time.Millisecond))
+			return results, nil
+		}
+		wait := opts.jitter(interval)
+		if time.Now().Add(wait).After(deadline) {
+			return results, fmt.Errorf("assertions did not pass after %d attempt(s) in %s: %w", attempt, time.Since(start).Round(time.Millisecond), err)
+		}
+		terratestLogger.Logf(t, "Assertions failed on attempt %d, retrying in %s: %v", attempt, wait.Round(time.Millisecond), err)
+		time.Sleep(wait)
+		interval = min(interval*2, opts.MaxInterval)
+	}
+}
+
+// AssertConsistently re-runs fetch at a fixed interval for the Duration window.
+// Fails the test if any assertion breaks during the window.
+//
+// The results of the last attempt are recorded in the test reports (see ReporterFor).
+func AssertConsistently(t *testing.T, fetch FetchFunc, assertions []Assertion, opts PollOptions) {
+	results, err := assertConsistently(t, fetch, assertions, opts)
+	ReporterFor(t).Record("", results...)
+	if err != nil {
+		t.Errorf("failed consistent assertions: %v", err)
+	}
+}
+
+// AssertConsistentlyE re-runs fetch at a fixed interval for the Duration window,
+// returns an error as soon as any assertion breaks during the window.
+func AssertConsistentlyE(t *testing.T, fetch FetchFunc, assertions []Assertion, opts PollOptions) error {
+	_, err := assertConsistently(t, fetch, assertions, opts)
+	return err
+}
+
+// assertConsistently returns the results of the last attempt of AssertConsistentlyE, nil if the fetch failed
+func assertConsistently(t *testing.T, fetch FetchFunc, assertions []Assertion, opts PollOptions) ([]AssertionResult, error) {
+	if opts.Duration <= 0 {
+		return nil, fmt.Errorf("duration must be positive")
+	}
+	opts = opts.withDefaults()
+	start := time.Now()
+	end := opts.deadline(t, start, opts.Duration)
+	for attempt := 1; ; attempt++ {
+		results, err := fetchAndEvaluate(fetch, assertions)
+		if err != nil {
+			return results, fmt.Errorf("assertions broke on attempt %d after %s: %w", attempt, time.Since(start).Round(time.Millisecond), err)
+		}
+		remaining := time.Until(end)
+		if remaining <= 0 {
+			terratestLogger.Logf(t, "Assertions held for %d attempt(s) in %s", attempt, time.Since(start).Round(time.Millisecond))
+			return results, nil
+		}
+		time.Sleep(min(opts.jitter(opts.Interval), remaining))
+	}
+}
+
+// fetchAndEvaluate returns the results of the assertions and their combined errors, nil results if fetch failed
+func fetchAndEvaluate(fetch FetchFunc, assertions []Assertion) ([]AssertionResult, error) {
+	input, err := fetch()
+	if err != nil {
+		return nil, fmt.Errorf("fetch failed: %w", err)
+	}
+	results, err := EvaluateE(input, assertions)
+	if err != nil {
+		return nil, err
+	}
+	return results, ResultsError(results)
+}
+
+func (o PollOptions) withDefaults() PollOptions {
+	if o.Interval <= 0 {
+		o.Interval = DefaultPollInterval
+	}
+	if o.MaxInterval <= 0 {
+		o.MaxInterval = DefaultPollMaxInterval
+	}
+	if o.MaxInterval < o.Interval {
+		o.MaxInterval = o.Interval
+	}
+	if o.Jitter <= 0 {
+		o.Jitter = DefaultPollJitter
+	} else if o.Jitter > 1 {
+		o.Jitter = 1
+	}
+	if o.DeadlineMargin <= 0 {
+		o.DeadlineMargin = DefaultDeadlineMargin
+	}
+	return o
+}
+
+// deadline returns start+window (DefaultPollTimeout if not set), capped by the test deadline minus the margin
+func (o PollOptions) deadline(t *testing.T, start time.Time, window time.Duration) time.Time {
+	if window <= 0 {
+		window = DefaultPollTimeout
+	}
+	deadline := start.Add(window)
+	if testDeadline, ok := t.Deadline(); ok {
+		if capped := testDeadline.Add(-o.DeadlineMargin); capped.Before(deadline) {
+			terratestLogger.Logf(t, "Polling capped at %s by test deadline %s", capped.Format(time.RFC3339), testDeadline.Format(time.RFC3339))
+			deadline = capped
+		}
+	}
+	return deadline
+}
+
+// jitter randomizes the interval by +/- the Jitter fraction
+func (o PollOptions) jitter(interval time.Duration) time.Duration {
+	delta := (rand.Float64()*2 - 1) * o.Jitter * float64(interval)
+	return interval + time.Duration(delta)
+}
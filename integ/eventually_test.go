@@ -0,0 +1,99 @@
+package integ
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fetchSequence returns a fetch function returning the given values in order, repeating the last value
+func fetchSequence(values ...any) (FetchFunc, *int) {
+	calls := 0
+	return func() (any, error) {
+		i := min(calls, len(values)-1)
+		calls++
+		if err, ok := values[i].(error); ok {
+			return nil, err
+		}
+		return values[i], nil
+	}, &calls
+}
+
+var fastPoll = PollOptions{
+	Interval:    time.Millisecond,
+	MaxInterval: 5 * time.Millisecond,
+}
+
+func TestAssertEventually_Success(t *testing.T) {
+	fetch, calls := fetchSequence(
+		fmt.Errorf("not yet"),
+		map[string]any{"status": "RUNNING"},
+		map[string]any{"status": "SUCCEEDED"},
+	)
+	AssertEventually(t, fetch, []Assertion{
+		{
+			Path:    "status",
+			Matcher: Equals("SUCCEEDED"),
+		},
+	}, fastPoll)
+	assert.Equal(t, 3, *calls)
+}
+
+func TestAssertEventually_Timeout(t *testing.T) {
+	fetch, calls := fetchSequence(map[string]any{"status": "RUNNING"})
+	opts := fastPoll
+	opts.Timeout = 50 * time.Millisecond
+	err := AssertEventuallyE(t, fetch, []Assertion{
+		{
+			Path:    "status",
+			Matcher: Equals("SUCCEEDED"),
+		},
+	}, opts)
+	assert.Error(t, err, "Expected error due to timeout")
+	assert.Greater(t, *calls, 1, "Expected fetch to be retried")
+}
+
+func TestAssertConsistently_Success(t *testing.T) {
+	fetch, calls := fetchSequence(map[string]any{"processed": []any{}})
+	opts := fastPoll
+	opts.Duration = 20 * time.Millisecond
+	AssertConsistently(t, fetch, []Assertion{
+		{
+			Path:    "processed",
+			Matcher: Not(ArrayWith([]string{"random message"})),
+		},
+	}, opts)
+	assert.Greater(t, *calls, 1, "Expected fetch to be repeated during the window")
+}
+
+func TestAssertConsistently_Failure(t *testing.T) {
+	fetch, calls := fetchSequence(
+		map[string]any{"processed": []any{}},
+		map[string]any{"processed": []any{"random message"}},
+		map[string]any{"processed": []any{}},
+	)
+	opts := fastPoll
+	opts.Duration = time.Second
+	err := AssertConsistentlyE(t, fetch, []Assertion{
+		{
+			Path:    "processed",
+			Matcher: Not(ArrayWith([]string{"random message"})),
+		},
+	}, opts)
+	assert.Error(t, err, "Expected error due to broken assertion")
+	assert.Equal(t, 2, *calls, "Expected to stop on the first broken assertion")
+}
+
+func TestAssertConsistently_FetchError(t *testing.T) {
+	fetch, _ := fetchSequence(fmt.Errorf("log group not found"))
+	opts := fastPoll
+	opts.Duration = time.Second
+	err := AssertConsistentlyE(t, fetch, nil, opts)
+	assert.Error(t, err, "Expected error due to failed fetch")
+
+	opts.Duration = 0
+	err = AssertConsistentlyE(t, fetch, nil, opts)
+	assert.Error(t, err, "Expected error due to missing duration")
+}
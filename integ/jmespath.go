@@ -0,0 +1,135 @@
+package integ
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jmespath/go-jmespath"
+)
+
+// JMESPathFunction is a custom function available to all JMESPath queries compiled by CompileJMESPath.
+type JMESPathFunction struct {
+	Name     string                        // Name of the function in JMESPath expressions
+	Args     string                        // Comma separated argument types, i.e. "string,string|array" (number, string, array, object, array[number], array[string], expref, any)
+	Variadic bool                          // Whether the last argument may be repeated
+	Handler  func(args []any) (any, error) // Function implementation, args are type checked against Args
+}
+
+var (
+	jmespathFunctionsMu sync.RWMutex
+	jmespathFunctions   = map[string]JMESPathFunction{}
+)
+
+func init() {
+	for _, fn := range []JMESPathFunction{
+		{Name: "lower", Args: "string", Handler: jpfLower},
+		{Name: "upper", Args: "string", Handler: jpfUpper},
+		{Name: "starts_with_ci", Args: "string,string", Handler: jpfStartsWithCI},
+		{Name: "from_json", Args: "string", Handler: jpfFromJSON},
+		{Name: "to_number_strict", Args: "any", Handler: jpfToNumberStrict},
+		{Name: "base64_decode", Args: "string", Handler: jpfBase64Decode},
+		{Name: "regex_match", Args: "string,string", Handler: jpfRegexMatch},
+	} {
+		if err := RegisterJMESPathFunction(fn); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterJMESPathFunction registers a custom function for JMESPath queries used by AssertE and TerraformOutputJMES.
+// Registering a function with the same name replaces the previous function.
+func RegisterJMESPathFunction(fn JMESPathFunction) error {
+	if fn.Name == "" {
+		return fmt.Errorf("function name cannot be empty")
+	}
+	if fn.Handler == nil {
+		return fmt.Errorf("function %s has no handler", fn.Name)
+	}
+	// validate argument types before adding the function to the registry
+	if err := jmespath.MustCompile("@").RegisterFunction(fn.Name, fn.Args, fn.Variadic, fn.Handler); err != nil {
+		return fmt.Errorf("invalid function %s: %v", fn.Name, err)
+	}
+	jmespathFunctionsMu.Lock()
+	defer jmespathFunctionsMu.Unlock()
+	jmespathFunctions[fn.Name] = fn
+	return nil
+}
+
+// CompileJMESPath compiles a JMESPath expression with all registered custom functions.
+func CompileJMESPath(expression string) (*jmespath.JMESPath, error) {
+	p, err := jmespath.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	jmespathFunctionsMu.RLock()
+	defer jmespathFunctionsMu.RUnlock()
+	for _, fn := range jmespathFunctions {
+		if err := p.RegisterFunction(fn.Name, fn.Args, fn.Variadic, fn.Handler); err != nil {
+			return nil, fmt.Errorf("error registering function %s: %v", fn.Name, err)
+		}
+	}
+	return p, nil
+}
+
+func jpfLower(args []any) (any, error) {
+	return strings.ToLower(args[0].(string)), nil
+}
+
+func jpfUpper(args []any) (any, error) {
+	return strings.ToUpper(args[0].(string)), nil
+}
+
+func jpfStartsWithCI(args []any) (any, error) {
+	return strings.HasPrefix(strings.ToLower(args[0].(string)), strings.ToLower(args[1].(string))), nil
+}
+
+// jpfFromJSON parses a JSON encoded string, i.e. a policy document or SQS message body
+func jpfFromJSON(args []any) (any, error) {
+	var result any
+	if err := json.Unmarshal([]byte(args[0].(string)), &result); err != nil {
+		return nil, fmt.Errorf("from_json: %v", err)
+	}
+	return result, nil
+}
+
+// jpfToNumberStrict converts a number or numeric string to a number, unlike to_number it fails instead of returning null
+func jpfToNumberStrict(args []any) (any, error) {
+	switch v := args[0].(type) {
+	case float64:
+		return v, nil
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, fmt.Errorf("to_number_strict: '%s' is not a number", v)
+		}
+		return n, nil
+	default:
+		return nil, fmt.Errorf("to_number_strict: '%v' is not a number", v)
+	}
+}
+
+// jpfBase64Decode decodes a standard base64 string, with or without padding
+func jpfBase64Decode(args []any) (any, error) {
+	s := args[0].(string)
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		decoded, err = base64.RawStdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("base64_decode: %v", err)
+		}
+	}
+	return string(decoded), nil
+}
+
+func jpfRegexMatch(args []any) (any, error) {
+	re, err := regexp.Compile(args[1].(string))
+	if err != nil {
+		return nil, fmt.Errorf("regex_match: invalid regexp '%s': %v", args[1], err)
+	}
+	return re.MatchString(args[0].(string)), nil
+}
@@ -0,0 +1,98 @@
+package integ
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJMESPathFunctions(t *testing.T) {
+	input := map[string]any{
+		"headers": map[string]any{
+			"Host":         "WWW.Example.com",
+			"Content-Type": "application/json",
+		},
+		"policy":   `{"Version": "2012-10-17", "Statement": [{"Effect": "Allow"}]}`,
+		"count":    "42",
+		"encoded":  "aGVsbG8gd29ybGQ=",
+		"unpadded": "aGVsbG8",
+		"arn":      "arn:aws:states:us-east-1:123456789012:stateMachine:test",
+	}
+	tests := []struct {
+		query         string
+		expectedValue any
+		description   string
+	}{
+		{"lower(headers.Host)", "www.example.com", "lower"},
+		{"upper(headers.Host)", "WWW.EXAMPLE.COM", "upper"},
+		{"keys(headers)[?lower(@) == 'content-type'] | [0]", "Content-Type", "case insensitive header"},
+		{"starts_with_ci(headers.Host, 'www.')", true, "starts_with_ci"},
+		{"from_json(policy).Statement[0].Effect", "Allow", "from_json"},
+		{"to_number_strict(count)", float64(42), "to_number_strict string"},
+		{"to_number_strict(`1.5`)", 1.5, "to_number_strict number"},
+		{"base64_decode(encoded)", "hello world", "base64_decode"},
+		{"base64_decode(unpadded)", "hello", "base64_decode without padding"},
+		{"regex_match(arn, '^arn:aws:states:[^:]+:\\d{12}:stateMachine:')", true, "regex_match"},
+		{"regex_match(arn, ':activity:')", false, "regex_match no match"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			p, err := CompileJMESPath(tt.query)
+			require.NoError(t, err)
+			result, err := p.Search(input)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedValue, result,
+				"JMESPath query %q should return %v, got %v", tt.query, tt.expectedValue, result)
+		})
+	}
+}
+
+func TestJMESPathFunctions_Errors(t *testing.T) {
+	input := map[string]any{
+		"name":   "not-a-number",
+		"policy": "{",
+	}
+	for _, query := range []string{
+		"to_number_strict(name)",
+		"to_number_strict(`true`)",
+		"from_json(policy)",
+		"base64_decode(name)",
+		"regex_match(name, '(')",
+		"lower(`1`)", // type checked
+	} {
+		t.Run(query, func(t *testing.T) {
+			p, err := CompileJMESPath(query)
+			require.NoError(t, err)
+			_, err = p.Search(input)
+			assert.Error(t, err, "Expected error for %q", query)
+		})
+	}
+}
+
+func TestRegisterJMESPathFunction(t *testing.T) {
+	err := RegisterJMESPathFunction(JMESPathFunction{
+		Name: "test_reverse_words",
+		Args: "string",
+		Handler: func(args []any) (any, error) {
+			words := strings.Fields(args[0].(string))
+			for i, j := 0, len(words)-1; i < j; i, j = i+1, j-1 {
+				words[i], words[j] = words[j], words[i]
+			}
+			return strings.Join(words, " "), nil
+		},
+	})
+	require.NoError(t, err)
+	Assert(t, map[string]any{"message": "world hello"}, []Assertion{
+		{
+			Path:    "test_reverse_words(message)",
+			Matcher: Equals("hello world"),
+		},
+	})
+
+	assert.Error(t, RegisterJMESPathFunction(JMESPathFunction{Name: "", Args: "string", Handler: jpfLower}), "Expected error due to empty name")
+	assert.Error(t, RegisterJMESPathFunction(JMESPathFunction{Name: "no_handler", Args: "string"}), "Expected error due to nil handler")
+	assert.Error(t, RegisterJMESPathFunction(JMESPathFunction{Name: "bad_args", Args: "str", Handler: jpfLower}), "Expected error due to invalid argument type")
+}
@@ -0,0 +1,270 @@
+package integ
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Matcher matches the value found at an Assertion Path.
+//
+// Values are compared through their JSON representation, so numbers are always
+// float64 and typed structs, pointers and slices compare by their JSON shape.
+type Matcher interface {
+	// Match returns an error describing the mismatch, or nil if the value matches
+	Match(value any) error
+	// String describes the matcher in failure messages
+	String() string
+}
+
+// JSONType is the JSON type of a value, named like the JMESPath `type()` function.
+type JSONType string
+
+const (
+	TypeString  JSONType = "string"
+	TypeNumber  JSONType = "number"
+	TypeBoolean JSONType = "boolean"
+	TypeArray   JSONType = "array"
+	TypeObject  JSONType = "object"
+	TypeNull    JSONType = "null"
+)
+
+// matcher is a Matcher backed by a function.
+type matcher struct {
+	desc     string
+	expected any // expected value reported in AssertionResult, defaults to desc
+	match    func(value any) error
+}
+
+func (m matcher) Match(value any) error {
+	return m.match(value)
+}
+
+func (m matcher) String() string {
+	return m.desc
+}
+
+func (m matcher) expectedValue() any {
+	if m.expected != nil {
+		return m.expected
+	}
+	return m.desc
+}
+
+// Regexp matches the string representation of a value against a regular expression.
+// If the value is an array, any element matching is considered a success.
+func Regexp(pattern string) Matcher {
+	return matcher{
+		desc:     fmt.Sprintf("regexp(%s)", pattern),
+		expected: pattern,
+		match: func(value any) error {
+			return assertRegexp(value, pattern)
+		},
+	}
+}
+
+// Equals matches a value which is equal to expected after converting both to JSON.
+func Equals(expected any) Matcher {
+	return matcher{
+		desc:     fmt.Sprintf("equals(%s)", toJSONString(expected)),
+		expected: expected,
+		match: func(value any) error {
+			want, err := toJSONValue(expected)
+			if err != nil {
+				return fmt.Errorf("invalid expected value: %v", err)
+			}
+			got, err := toJSONValue(value)
+			if err != nil {
+				return err
+			}
+			if !reflect.DeepEqual(want, got) {
+				return fmt.Errorf("value '%s' does not equal '%s'", toJSONString(got), toJSONString(want))
+			}
+			return nil
+		},
+	}
+}
+
+// GreaterThan matches a number strictly greater than n.
+func GreaterThan(n float64) Matcher {
+	return numberMatcher(fmt.Sprintf("gt(%v)", n), func(v float64) bool { return v > n })
+}
+
+// LessThan matches a number strictly less than n.
+func LessThan(n float64) Matcher {
+	return numberMatcher(fmt.Sprintf("lt(%v)", n), func(v float64) bool { return v < n })
+}
+
+// Between matches a number within the inclusive range [min, max].
+func Between(min, max float64) Matcher {
+	return numberMatcher(fmt.Sprintf("between(%v, %v)", min, max), func(v float64) bool { return v >= min && v <= max })
+}
+
+// numberMatcher matches a number against a predicate, non-numbers never match.
+func numberMatcher(desc string, predicate func(v float64) bool) Matcher {
+	return matcher{
+		desc: desc,
+		match: func(value any) error {
+			v, err := toJSONValue(value)
+			if err != nil {
+				return err
+			}
+			n, ok := v.(float64)
+			if !ok {
+				return fmt.Errorf("value '%s' is not a number", toJSONString(v))
+			}
+			if !predicate(n) {
+				return fmt.Errorf("value '%v' does not match %s", n, desc)
+			}
+			return nil
+		},
+	}
+}
+
+// IsType matches a value of the given JSON type.
+func IsType(expected JSONType) Matcher {
+	return matcher{
+		desc: fmt.Sprintf("type(%s)", expected),
+		match: func(value any) error {
+			v, err := toJSONValue(value)
+			if err != nil {
+				return err
+			}
+			if got := jsonTypeOf(v); got != expected {
+				return fmt.Errorf("value '%s' is of type '%s', expected '%s'", toJSONString(v), got, expected)
+			}
+			return nil
+		},
+	}
+}
+
+// HasPrefix matches a string starting with prefix.
+func HasPrefix(prefix string) Matcher {
+	return stringMatcher(fmt.Sprintf("prefix(%s)", prefix), func(s string) bool { return strings.HasPrefix(s, prefix) })
+}
+
+// HasSuffix matches a string ending with suffix.
+func HasSuffix(suffix string) Matcher {
+	return stringMatcher(fmt.Sprintf("suffix(%s)", suffix), func(s string) bool { return strings.HasSuffix(s, suffix) })
+}
+
+// stringMatcher matches a string against a predicate, non-strings never match.
+func stringMatcher(desc string, predicate func(s string) bool) Matcher {
+	return matcher{
+		desc: desc,
+		match: func(value any) error {
+			v, err := toJSONValue(value)
+			if err != nil {
+				return err
+			}
+			s, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("value '%s' is not a string", toJSONString(v))
+			}
+			if !predicate(s) {
+				return fmt.Errorf("value '%s' does not match %s", s, desc)
+			}
+			return nil
+		},
+	}
+}
+
+// And matches a value matching all matchers.
+func And(matchers ...Matcher) Matcher {
+	return matcher{
+		desc: joinMatchers("and", matchers),
+		match: func(value any) error {
+			for _, m := range matchers {
+				if err := m.Match(value); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// Or matches a value matching at least one of the matchers.
+func Or(matchers ...Matcher) Matcher {
+	desc := joinMatchers("or", matchers)
+	return matcher{
+		desc: desc,
+		match: func(value any) error {
+			var errs []string
+			for _, m := range matchers {
+				err := m.Match(value)
+				if err == nil {
+					return nil
+				}
+				errs = append(errs, err.Error())
+			}
+			return fmt.Errorf("value does not match %s: [%s]", desc, strings.Join(errs, "; "))
+		},
+	}
+}
+
+// Not matches a value which does not match m.
+func Not(m Matcher) Matcher {
+	desc := fmt.Sprintf("not(%s)", m)
+	return matcher{
+		desc: desc,
+		match: func(value any) error {
+			if err := m.Match(value); err == nil {
+				return fmt.Errorf("value '%s' matches %s", toJSONString(value), m)
+			}
+			return nil
+		},
+	}
+}
+
+func joinMatchers(op string, matchers []Matcher) string {
+	descs := make([]string, len(matchers))
+	for i, m := range matchers {
+		descs[i] = m.String()
+	}
+	return fmt.Sprintf("%s(%s)", op, strings.Join(descs, ", "))
+}
+
+// toJSONValue converts a value to its generic JSON representation
+// (nil, bool, float64, string, []interface{} or map[string]interface{}).
+func toJSONValue(value any) (any, error) {
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value to JSON: %v", err)
+	}
+	var result any
+	if err := json.Unmarshal(jsonData, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+	return result, nil
+}
+
+// toJSONString returns the compact JSON of a value, for failure messages.
+func toJSONString(value any) string {
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(jsonData)
+}
+
+// jsonTypeOf returns the JSON type of a value returned by toJSONValue.
+func jsonTypeOf(value any) JSONType {
+	switch value.(type) {
+	case nil:
+		return TypeNull
+	case bool:
+		return TypeBoolean
+	case float64:
+		return TypeNumber
+	case string:
+		return TypeString
+	case []interface{}:
+		return TypeArray
+	case map[string]interface{}:
+		return TypeObject
+	default:
+		return JSONType(fmt.Sprintf("%T", value))
+	}
+}
@@ -0,0 +1,108 @@
+package integ
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatcher_Success(t *testing.T) {
+	Assert(t, testObject, []Assertion{
+		{
+			// int in the input compares equal to float64 after JSON conversion
+			Path:    "status",
+			Matcher: Equals(200),
+		},
+		{
+			Path:    "request.querystring.test.value",
+			Matcher: Equals(true),
+		},
+		{
+			Path: "request.querystring.arg",
+			Matcher: Equals(map[string]any{
+				"multiValue": []map[string]string{{"value": "val1"}, {"value": "val2"}},
+				"value":      "val1",
+			}),
+		},
+		{
+			Path:    "status",
+			Matcher: And(GreaterThan(199), LessThan(300), Between(200, 200)),
+		},
+		{
+			Path:    "request.cookies.loggedIn.value",
+			Matcher: IsType(TypeBoolean),
+		},
+		{
+			Path:    "request.headers.accept.multiValue",
+			Matcher: IsType(TypeArray),
+		},
+		{
+			Path:    "request.uri",
+			Matcher: And(HasPrefix("/"), HasSuffix(".html")),
+		},
+		{
+			Path:    "request.method",
+			Matcher: Or(Equals("POST"), Equals("GET")),
+		},
+		{
+			Path:    "request.method",
+			Matcher: Not(Equals("POST")),
+		},
+		{
+			// ExpectedRegexp is combined with the Matcher
+			Path:           "request.uri",
+			ExpectedRegexp: strPtr("index"),
+			Matcher:        IsType(TypeString),
+		},
+	})
+}
+
+func TestMatcher_Failure(t *testing.T) {
+	tests := []struct {
+		description string
+		assertion   Assertion
+	}{
+		{"equals", Assertion{Path: "status", Matcher: Equals("200")}},
+		{"gt", Assertion{Path: "status", Matcher: GreaterThan(200)}},
+		{"lt", Assertion{Path: "status", Matcher: LessThan(200)}},
+		{"between", Assertion{Path: "status", Matcher: Between(300, 399)}},
+		{"not a number", Assertion{Path: "request.uri", Matcher: GreaterThan(0)}},
+		{"type", Assertion{Path: "status", Matcher: IsType(TypeString)}},
+		{"prefix", Assertion{Path: "request.uri", Matcher: HasPrefix("index")}},
+		{"suffix", Assertion{Path: "request.uri", Matcher: HasSuffix(".htm")}},
+		{"not a string", Assertion{Path: "status", Matcher: HasPrefix("2")}},
+		{"and", Assertion{Path: "status", Matcher: And(GreaterThan(0), IsType(TypeString))}},
+		{"or", Assertion{Path: "request.method", Matcher: Or(Equals("POST"), Equals("PUT"))}},
+		{"not", Assertion{Path: "request.method", Matcher: Not(Equals("GET"))}},
+		{"missing", Assertion{Path: "request.querystring.foo", Matcher: IsType(TypeNull)}},
+		{"regexp and matcher", Assertion{Path: "request.uri", ExpectedRegexp: strPtr("index"), Matcher: HasPrefix("index")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			err := AssertE(testObject, []Assertion{tt.assertion})
+			assert.Error(t, err, "Expected error due to failed matcher %s", tt.assertion.Matcher)
+		})
+	}
+}
+
+func TestMatcher_Regexp(t *testing.T) {
+	Assert(t, testObject, []Assertion{
+		{
+			Path:    "request.querystring.arg.multiValue[*].value",
+			Matcher: Regexp("^val2$"),
+		},
+	})
+	err := AssertE(testObject, []Assertion{
+		{
+			Path:    "request.uri",
+			Matcher: Regexp("("),
+		},
+	})
+	assert.Error(t, err, "Expected error due to invalid regexp")
+}
+
+func TestMatcher_String(t *testing.T) {
+	m := And(Equals(map[string]any{"a": 1}), Or(GreaterThan(1), Not(HasPrefix("x"))))
+	assert.Equal(t, `and(equals({"a":1}), or(gt(1), not(prefix(x))))`, m.String())
+}
@@ -0,0 +1,81 @@
+package integ
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+var (
+	jsonStringFieldsMu sync.RWMutex
+	// Fields holding JSON encoded strings, i.e. IAM policy documents of util.Role and util.ManagedPolicy
+	jsonStringFields = []string{"assumeRolePolicyDocument", "policyDocument", "policy"}
+)
+
+// RegisterJSONStringField registers a field name (case-insensitive) whose JSON encoded string values are decoded by Normalize.
+func RegisterJSONStringField(name string) {
+	jsonStringFieldsMu.Lock()
+	defer jsonStringFieldsMu.Unlock()
+	jsonStringFields = append(jsonStringFields, name)
+}
+
+// Normalize converts any value (typed structs, pointers, slices of maps, ...) to its generic JSON representation
+// which can be searched with JMESPath.
+//
+// JSON encoded strings in registered fields, and the given additional fields, are decoded at any depth.
+// AssertE normalizes its input, use Normalize directly to decode additional fields, i.e. `Output` of util.SfnExecutionOutput.
+func Normalize(input any, additionalJSONStringFields ...string) (any, error) {
+	value, err := toJSONValue(input)
+	if err != nil {
+		return nil, err
+	}
+	jsonStringFieldsMu.RLock()
+	fields := append(append([]string{}, jsonStringFields...), additionalJSONStringFields...)
+	jsonStringFieldsMu.RUnlock()
+	return decodeJSONStringFields(value, fields), nil
+}
+
+// decodeJSONStringFields replaces JSON encoded object and array strings of the given fields with their decoded value
+func decodeJSONStringFields(value any, fields []string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, elem := range v {
+			if s, ok := elem.(string); ok && isJSONStringField(k, fields) {
+				if decoded, ok := decodeJSONString(s); ok {
+					elem = decoded
+				}
+			}
+			v[k] = decodeJSONStringFields(elem, fields)
+		}
+		return v
+	case []any:
+		for i, elem := range v {
+			v[i] = decodeJSONStringFields(elem, fields)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+func isJSONStringField(key string, fields []string) bool {
+	for _, f := range fields {
+		if strings.EqualFold(key, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeJSONString decodes strings holding a JSON object or array, other strings are not decoded
+func decodeJSONString(s string) (any, bool) {
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return nil, false
+	}
+	var result any
+	if err := json.Unmarshal([]byte(trimmed), &result); err != nil {
+		return nil, false
+	}
+	return result, true
+}
@@ -0,0 +1,122 @@
+package integ
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// typed structs similar to integ/aws helper results
+type testPolicy struct {
+	PolicyName     string `json:"policyName"`
+	PolicyDocument string `json:"policyDocument"`
+}
+
+type testRole struct {
+	Arn                      string       `json:"arn"`
+	AssumeRolePolicyDocument string       `json:"assumeRolePolicyDocument"`
+	CreateDate               time.Time    `json:"createDate"`
+	MaxSessionDuration       int32        `json:"maxSessionDuration"`
+	InlinePolicies           []testPolicy `json:"inlinePolicies"`
+	Description              *string      `json:"description"`
+}
+
+type testExecutionOutput struct {
+	Status string
+	Output string
+}
+
+var testRoleObject = &testRole{
+	Arn:                      "arn:aws:iam::123456789012:role/test",
+	AssumeRolePolicyDocument: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"lambda.amazonaws.com"},"Action":"sts:AssumeRole"}]}`,
+	CreateDate:               time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+	MaxSessionDuration:       3600000,
+	InlinePolicies: []testPolicy{
+		{
+			PolicyName:     "inline",
+			PolicyDocument: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"*"}]}`,
+		},
+	},
+	Description: strPtr("test role"),
+}
+
+func TestNormalize_TypedStruct(t *testing.T) {
+	Assert(t, testRoleObject, []Assertion{
+		{
+			Path:    "assumeRolePolicyDocument.Statement[0].Principal.Service",
+			Matcher: Equals("lambda.amazonaws.com"),
+		},
+		{
+			Path:    "inlinePolicies[?policyName=='inline'].policyDocument.Statement[].Action",
+			Matcher: ArrayWith([]string{"s3:GetObject"}),
+		},
+		{
+			Path:           "maxSessionDuration",
+			ExpectedRegexp: strPtr("^3600000$"),
+		},
+		{
+			Path:           "createDate",
+			ExpectedRegexp: strPtr("^2024-11-01T"),
+		},
+		{
+			Path:    "description",
+			Matcher: Equals("test role"),
+		},
+	})
+}
+
+func TestNormalize_SliceOfMaps(t *testing.T) {
+	input := []map[string]any{
+		{"name": "first", "value": strPtr("1")},
+		{"name": "second", "value": strPtr("2")},
+	}
+	Assert(t, input, []Assertion{
+		{
+			Path:    "[?name=='second'].value | [0]",
+			Matcher: Equals("2"),
+		},
+	})
+}
+
+func TestNormalize_AdditionalFields(t *testing.T) {
+	result := testExecutionOutput{
+		Status: "SUCCEEDED",
+		Output: `{"Body": "hello world!"}`,
+	}
+	// Output is not decoded unless requested
+	Assert(t, result, []Assertion{
+		{
+			Path:    "Output",
+			Matcher: IsType(TypeString),
+		},
+	})
+
+	normalized, err := Normalize(result, "Output")
+	require.NoError(t, err)
+	Assert(t, normalized, []Assertion{
+		{
+			Path:    "Output.Body",
+			Matcher: Equals("hello world!"),
+		},
+	})
+}
+
+func TestNormalize_InvalidJSONString(t *testing.T) {
+	normalized, err := Normalize(map[string]any{
+		"policy": "{not json",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"policy": "{not json"}, normalized)
+}
+
+func TestNormalize_Error(t *testing.T) {
+	err := AssertE(map[string]any{"fn": func() {}}, []Assertion{
+		{
+			Path:   "fn",
+			Exists: true,
+		},
+	})
+	assert.Error(t, err, "Expected error due to input which cannot be converted to JSON")
+}
@@ -0,0 +1,219 @@
+package integ
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/envtio/base/integ/redact"
+	"github.com/envtio/base/integ/tfoutput"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/require"
+)
+
+// Outputs is a snapshot of all Terraform outputs which can be searched with JMESPath and bound to structs.
+//
+// Sensitive outputs are masked, use RevealSensitive to access their raw values.
+type Outputs struct {
+	values    map[string]any // output values, sensitive outputs masked
+	revealed  map[string]any // raw output values
+	sensitive map[string]bool
+}
+
+// NewOutputs returns an Outputs snapshot of the given output values, the values of the sensitive output names are masked.
+//
+// The raw values are not registered for redaction in logs, see redact.RegisterValue.
+func NewOutputs(values map[string]any, sensitive ...string) *Outputs {
+	if values == nil {
+		values = map[string]any{}
+	}
+	o := &Outputs{
+		values:    make(map[string]any, len(values)),
+		revealed:  values,
+		sensitive: make(map[string]bool, len(sensitive)),
+	}
+	for _, name := range sensitive {
+		o.sensitive[name] = true
+	}
+	for name, value := range values {
+		if o.sensitive[name] {
+			value = redact.MaskValue(value)
+		}
+		o.values[name] = value
+	}
+	return o
+}
+
+// outputsCacheEntry is the Outputs of a cached tfoutput snapshot
+type outputsCacheEntry struct {
+	outputs  *Outputs
+	snapshot *tfoutput.Snapshot
+}
+
+var (
+	outputsCacheMu sync.Mutex
+	outputsCache   = map[string]outputsCacheEntry{}
+)
+
+// LoadOutputs loads all Terraform outputs once and returns the cached snapshot on subsequent calls.
+// This fails the test on any errors.
+func LoadOutputs(t *testing.T, terraformOptions *terraform.Options) *Outputs {
+	outputs, err := LoadOutputsE(t, terraformOptions)
+	require.NoError(t, err)
+	return outputs
+}
+
+// LoadOutputsE loads all Terraform outputs once and returns the cached snapshot on subsequent calls,
+// the values of sensitive outputs are registered for redaction in logs until the test completes.
+//
+// The snapshot is reloaded when the local state changes (i.e. after apply),
+// call InvalidateOutputs after applying with a remote backend. See tfoutput.LoadE.
+func LoadOutputsE(t *testing.T, terraformOptions *terraform.Options) (*Outputs, error) {
+	snapshot, err := tfoutput.LoadE(t, terraformOptions)
+	if err != nil {
+		return nil, err
+	}
+	key := snapshot.Dir
+	outputsCacheMu.Lock()
+	defer outputsCacheMu.Unlock()
+	if entry, ok := outputsCache[key]; ok && entry.snapshot == snapshot {
+		return entry.outputs, nil
+	}
+	outputs := NewOutputs(snapshot.Values(), snapshot.Sensitive()...)
+	outputsCache[key] = outputsCacheEntry{outputs: outputs, snapshot: snapshot}
+	return outputs, nil
+}
+
+// InvalidateOutputs removes the cached outputs snapshot of the Terraform working dir.
+func InvalidateOutputs(terraformOptions *terraform.Options) {
+	tfoutput.Invalidate(terraformOptions)
+}
+
+// Values returns the output values, sensitive outputs are masked.
+func (o *Outputs) Values() map[string]any {
+	return o.values
+}
+
+// IsSensitive returns true if the output is sensitive.
+func (o *Outputs) IsSensitive(name string) bool {
+	return o.sensitive[name]
+}
+
+// Search searches the outputs using JMESPath, custom functions registered with RegisterJMESPathFunction are available.
+// Values of sensitive outputs are masked.
+func (o *Outputs) Search(query string) (any, error) {
+	return searchValues(o.values, query)
+}
+
+// RevealSensitive searches the raw outputs, including the values of sensitive outputs, using JMESPath.
+// This fails the test on any errors.
+func (o *Outputs) RevealSensitive(t *testing.T, query string) any {
+	value, err := o.RevealSensitiveE(query)
+	require.NoError(t, err)
+	return value
+}
+
+// RevealSensitiveE searches the raw outputs, including the values of sensitive outputs, using JMESPath.
+//
+// The revealed values remain masked in logs.
+func (o *Outputs) RevealSensitiveE(query string) (any, error) {
+	return searchValues(o.revealed, query)
+}
+
+func searchValues(values map[string]any, query string) (any, error) {
+	p, err := CompileJMESPath(query)
+	if err != nil {
+		return nil, err
+	}
+	return p.Search(values)
+}
+
+// String searches the outputs using JMESPath and ensures the result is a non empty string. This fails the test on any errors
+func (o *Outputs) String(t *testing.T, query string) string {
+	value, err := o.Search(query)
+	require.NoError(t, err)
+	s, ok := value.(string)
+	require.True(t, ok && s != "", "Output %s should be a non empty string, got %v", query, value)
+	return s
+}
+
+// Bind sets the fields of the struct pointed to by target from the outputs. This fails the test on any errors
+func (o *Outputs) Bind(t *testing.T, target any) {
+	require.NoError(t, o.BindE(target))
+}
+
+// BindE sets the fields of the struct pointed to by target from the outputs, using the JMESPath in the `jmes` field tag:
+//
+//	type appOutputs struct {
+//		StateMachineArn string            `jmes:"state_machine.arn"`
+//		QueueURL        string            `jmes:"queue.url,optional"`
+//		Tags            map[string]string `jmes:"state_machine.tags"`
+//		SecretKey       string            `jmes:"jwt.secret_key,sensitive"`
+//	}
+//
+// Fields are required unless tagged `optional`, required fields must not be missing, null or an empty string.
+// Sensitive outputs are masked unless the field is tagged `sensitive`, which reveals the raw value.
+// Values are converted to the field type through JSON, fields without tag are ignored.
+func (o *Outputs) BindE(target any) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind target must be a non-nil pointer to a struct, got %T", target)
+	}
+	v = v.Elem()
+	var combinedErr error
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		tag, ok := field.Tag.Lookup("jmes")
+		if !ok || tag == "-" {
+			continue
+		}
+		if !field.IsExported() {
+			combinedErr = multierror.Append(combinedErr, fmt.Errorf("field %s with jmes tag must be exported", field.Name))
+			continue
+		}
+		query, optional, reveal := parseJMESTag(tag)
+		search := o.Search
+		if reveal {
+			search = o.RevealSensitiveE
+		}
+		value, err := search(query)
+		if err != nil {
+			combinedErr = multierror.Append(combinedErr, fmt.Errorf("field %s: error searching JMESPath '%s': %v", field.Name, query, err))
+			continue
+		}
+		if value == nil || value == "" {
+			if !optional {
+				combinedErr = multierror.Append(combinedErr, fmt.Errorf("field %s: required output '%s' is missing or empty", field.Name, query))
+			}
+			continue
+		}
+		jsonData, err := json.Marshal(value)
+		if err != nil {
+			combinedErr = multierror.Append(combinedErr, fmt.Errorf("field %s: %v", field.Name, err))
+			continue
+		}
+		if err := json.Unmarshal(jsonData, v.Field(i).Addr().Interface()); err != nil {
+			combinedErr = multierror.Append(combinedErr, fmt.Errorf("field %s: cannot convert output '%s' to %s: %v", field.Name, query, field.Type, err))
+		}
+	}
+	return combinedErr
+}
+
+// parseJMESTag splits a `jmes` tag into the query and the optional and sensitive flags,
+// the query may contain commas (i.e. `join(',', names)`) so only known trailing options are removed
+func parseJMESTag(tag string) (query string, optional, sensitive bool) {
+	query = tag
+	for {
+		if q, ok := strings.CutSuffix(query, ",optional"); ok {
+			query, optional = q, true
+		} else if q, ok := strings.CutSuffix(query, ",sensitive"); ok {
+			query, sensitive = q, true
+		} else {
+			return query, optional, sensitive
+		}
+	}
+}
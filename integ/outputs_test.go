@@ -0,0 +1,139 @@
+package integ
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/envtio/base/integ/redact"
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testOutputs mirrors the outputs of fixtures/terraform-output-all
+var testOutputs = map[string]any{
+	"stars":    []any{"Sirius", "Rigel", "Betelgeuse"},
+	"our_star": "Sun",
+	"constellations": map[string]any{
+		"Gemini":  "Pollux",
+		"Scorpio": "Antares",
+		"Taurus":  "Aldebaran",
+		"Virgo":   "Spica",
+	},
+	"magnitudes": map[string]any{
+		"Sirius":  -1.46,
+		"Canopus": -0.72,
+		"Antares": 0.96,
+	},
+}
+
+func TestOutputs_Bind(t *testing.T) {
+	var bound struct {
+		OurStar        string             `jmes:"our_star"`
+		Stars          []string           `jmes:"stars"`
+		Brightest      string             `jmes:"stars[0]"`
+		Constellations map[string]string  `jmes:"constellations"`
+		Magnitudes     map[string]float64 `jmes:"magnitudes"`
+		Pollux         string             `jmes:"join(',', [constellations.Gemini, 'Gemini'])"`
+		Missing        string             `jmes:"missing.value,optional"`
+		Ignored        string
+	}
+	NewOutputs(testOutputs).Bind(t, &bound)
+	assert.Equal(t, "Sun", bound.OurStar)
+	assert.Equal(t, []string{"Sirius", "Rigel", "Betelgeuse"}, bound.Stars)
+	assert.Equal(t, "Sirius", bound.Brightest)
+	assert.Equal(t, "Aldebaran", bound.Constellations["Taurus"])
+	assert.Equal(t, -1.46, bound.Magnitudes["Sirius"])
+	assert.Equal(t, "Pollux,Gemini", bound.Pollux)
+	assert.Empty(t, bound.Missing)
+	assert.Empty(t, bound.Ignored)
+}
+
+func TestOutputs_BindErrors(t *testing.T) {
+	outputs := NewOutputs(testOutputs)
+	var bound struct {
+		Missing   string `jmes:"missing.value"`
+		WrongType int    `jmes:"our_star"`
+		Invalid   string `jmes:"stars[?"`
+		Optional  string `jmes:"missing,optional"`
+	}
+	err := outputs.BindE(&bound)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "field Missing: required output 'missing.value' is missing or empty")
+	assert.Contains(t, err.Error(), "field WrongType: cannot convert output 'our_star' to int")
+	assert.Contains(t, err.Error(), "field Invalid: error searching JMESPath 'stars[?'")
+	assert.NotContains(t, err.Error(), "Optional")
+
+	assert.Error(t, outputs.BindE(bound), "Expected error for non pointer target")
+	assert.Error(t, outputs.BindE(new(string)), "Expected error for non struct target")
+}
+
+func TestOutputs_Sensitive(t *testing.T) {
+	outputs := NewOutputs(map[string]any{
+		"jwt": map[string]any{
+			"secret_key": "terratest-test-secret",
+			"issuer":     "terratest",
+		},
+		"our_star": "Sun",
+	}, "jwt")
+	assert.True(t, outputs.IsSensitive("jwt"))
+	assert.False(t, outputs.IsSensitive("our_star"))
+	assert.Equal(t, "***", outputs.String(t, "jwt.secret_key"))
+	assert.Equal(t, "terratest-test-secret", outputs.RevealSensitive(t, "jwt.secret_key"))
+	assert.Equal(t, "Sun", outputs.RevealSensitive(t, "our_star"))
+	assert.Equal(t, "terratest-test-secret", redact.String("terratest-test-secret"), "Expected NewOutputs not to register sensitive values")
+
+	var bound struct {
+		Masked    string `jmes:"jwt.secret_key"`
+		SecretKey string `jmes:"jwt.secret_key,sensitive"`
+		Issuer    string `jmes:"jwt.issuer,optional,sensitive"`
+		Missing   string `jmes:"jwt.missing,sensitive,optional"`
+	}
+	outputs.Bind(t, &bound)
+	assert.Equal(t, "***", bound.Masked)
+	assert.Equal(t, "terratest-test-secret", bound.SecretKey)
+	assert.Equal(t, "terratest", bound.Issuer)
+	assert.Empty(t, bound.Missing)
+}
+
+func TestOutputs_Search(t *testing.T) {
+	outputs := NewOutputs(testOutputs)
+	value, err := outputs.Search("constellations.keys(@) | sort(@)")
+	require.NoError(t, err)
+	assert.Equal(t, []any{"Gemini", "Scorpio", "Taurus", "Virgo"}, value)
+	assert.Equal(t, "Sun", outputs.String(t, "our_star"))
+	assert.Equal(t, "sun", outputs.String(t, "lower(our_star)"))
+}
+
+func TestOutputs_Cache(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerraformFolderToTemp("./fixtures/terraform-output-all", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := &terraform.Options{
+		TerraformDir: testFolder,
+	}
+
+	terraform.InitAndApply(t, options)
+	outputs := LoadOutputs(t, options)
+	assert.Equal(t, "Sun", outputs.String(t, "our_star"))
+	assert.Same(t, outputs, LoadOutputs(t, options), "Expected cached outputs")
+
+	// apply changes the local state
+	outputTf := filepath.Join(testFolder, "output.tf")
+	contents, err := os.ReadFile(outputTf)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(outputTf, append(contents, []byte("\noutput \"comet\" {\n  value = \"Halley\"\n}\n")...), 0644))
+	terraform.Apply(t, options)
+	reloaded := LoadOutputs(t, options)
+	assert.NotSame(t, outputs, reloaded, "Expected outputs to reload after apply")
+	assert.Equal(t, "Halley", reloaded.String(t, "comet"))
+
+	InvalidateOutputs(options)
+	assert.NotSame(t, reloaded, LoadOutputs(t, options), "Expected outputs to reload after invalidation")
+}
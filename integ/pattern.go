@@ -0,0 +1,370 @@
+package integ
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// ref https://github.com/aws/aws-cdk/blob/v2.161.1/packages/aws-cdk-lib/assertions/lib/match.ts
+
+// ObjectLike matches an object containing at least the keys of the pattern.
+// Nested objects are matched partially as well, arrays must match exactly.
+//
+// The pattern is a Go value or a JSON literal and may contain nested Matchers.
+func ObjectLike(pattern any) Matcher {
+	return newPatternMatcher("objectLike", pattern, true, false)
+}
+
+// ArrayWith matches an array containing the pattern elements in order, other elements may appear in between.
+//
+// The pattern is a Go slice or a JSON literal and may contain nested Matchers.
+func ArrayWith(pattern any) Matcher {
+	return newPatternMatcher("arrayWith", pattern, false, true)
+}
+
+// Exact matches a value deeply equal to the pattern, without extra keys or elements.
+//
+// Unlike Equals, the pattern may contain nested Matchers.
+// Use json.RawMessage to provide the pattern as a JSON literal.
+func Exact(pattern any) Matcher {
+	return newPatternMatcher("exact", pattern, false, false)
+}
+
+// Absent matches a value which is missing or null.
+func Absent() Matcher {
+	return absentMatcher{}
+}
+
+// AnyValue matches any value which is not missing or null.
+func AnyValue() Matcher {
+	return matcher{
+		desc: "anyValue()",
+		match: func(value any) error {
+			if value == nil {
+				return fmt.Errorf("value is missing")
+			}
+			return nil
+		},
+	}
+}
+
+type absentMatcher struct{}
+
+func (absentMatcher) Match(value any) error {
+	if value != nil {
+		return fmt.Errorf("value '%s' should be absent", toJSONString(value))
+	}
+	return nil
+}
+
+func (absentMatcher) String() string {
+	return "absent()"
+}
+
+// isAbsent returns true if the matcher accepts missing values
+func isAbsent(m Matcher) bool {
+	_, ok := m.(absentMatcher)
+	return ok
+}
+
+// patternMatcher recursively matches a value against a pattern.
+type patternMatcher struct {
+	name           string
+	pattern        any
+	err            error // error normalizing the pattern
+	partialObjects bool  // objects may have keys which are not in the pattern
+	subsequence    bool  // top level array may have elements which are not in the pattern
+}
+
+// mismatch is a single failure found while matching a pattern.
+type mismatch struct {
+	path string
+	msg  string
+}
+
+func newPatternMatcher(name string, pattern any, partialObjects, subsequence bool) Matcher {
+	m := patternMatcher{
+		name:           name,
+		partialObjects: partialObjects,
+		subsequence:    subsequence,
+	}
+	// strings can only be JSON literals for objects and arrays
+	if s, ok := pattern.(string); ok && (partialObjects || subsequence) {
+		pattern = json.RawMessage(s)
+	}
+	m.pattern, m.err = toPattern(pattern)
+	return m
+}
+
+func (m patternMatcher) String() string {
+	return fmt.Sprintf("%s(%s)", m.name, toJSONString(describePattern(m.pattern)))
+}
+
+func (m patternMatcher) expectedValue() any {
+	return describePattern(m.pattern)
+}
+
+func (m patternMatcher) Match(value any) error {
+	if m.err != nil {
+		return fmt.Errorf("invalid %s pattern: %v", m.name, m.err)
+	}
+	actual, err := toJSONValue(value)
+	if err != nil {
+		return err
+	}
+	var mismatches []mismatch
+	if m.subsequence {
+		mismatches = m.matchSubsequence("", m.pattern, actual)
+	} else {
+		mismatches = m.match("", m.pattern, actual)
+	}
+	if len(mismatches) == 0 {
+		return nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "value does not match %s:", m.name)
+	for _, mm := range mismatches {
+		fmt.Fprintf(&sb, "\n  at '%s': %s", mm.path, mm.msg)
+	}
+	if !m.subsequence {
+		if diff := m.diff(actual); diff != "" {
+			fmt.Fprintf(&sb, "\n(-want +got):\n%s", diff)
+		}
+	}
+	return fmt.Errorf("%s", sb.String())
+}
+
+// match returns all mismatches between pattern and actual
+func (m patternMatcher) match(path string, pattern, actual any) []mismatch {
+	switch p := pattern.(type) {
+	case Matcher:
+		if err := p.Match(actual); err != nil {
+			return []mismatch{{pathOrRoot(path), err.Error()}}
+		}
+		return nil
+	case map[string]any:
+		obj, ok := actual.(map[string]any)
+		if !ok {
+			return []mismatch{{pathOrRoot(path), fmt.Sprintf("expected an object, got %s", toJSONString(actual))}}
+		}
+		var result []mismatch
+		for _, k := range sortedKeys(p) {
+			keyPath := joinPath(path, k)
+			v, found := obj[k]
+			if pm, ok := p[k].(Matcher); ok && isAbsent(pm) {
+				if found && v != nil {
+					result = append(result, mismatch{keyPath, fmt.Sprintf("key should be absent, got %s", toJSONString(v))})
+				}
+				continue
+			}
+			if !found {
+				result = append(result, mismatch{keyPath, "missing key"})
+				continue
+			}
+			result = append(result, m.match(keyPath, p[k], v)...)
+		}
+		if !m.partialObjects {
+			for _, k := range sortedKeys(obj) {
+				if _, found := p[k]; !found {
+					result = append(result, mismatch{joinPath(path, k), fmt.Sprintf("unexpected key with value %s", toJSONString(obj[k]))})
+				}
+			}
+		}
+		return result
+	case []any:
+		arr, ok := actual.([]any)
+		if !ok {
+			return []mismatch{{pathOrRoot(path), fmt.Sprintf("expected an array, got %s", toJSONString(actual))}}
+		}
+		if len(arr) != len(p) {
+			return []mismatch{{pathOrRoot(path), fmt.Sprintf("expected array of length %d, got %d", len(p), len(arr))}}
+		}
+		var result []mismatch
+		for i := range p {
+			result = append(result, m.match(fmt.Sprintf("%s[%d]", path, i), p[i], arr[i])...)
+		}
+		return result
+	default:
+		if !reflect.DeepEqual(pattern, actual) {
+			return []mismatch{{pathOrRoot(path), fmt.Sprintf("expected %s, got %s", toJSONString(pattern), toJSONString(actual))}}
+		}
+		return nil
+	}
+}
+
+// matchSubsequence matches the pattern elements in order against the actual array
+func (m patternMatcher) matchSubsequence(path string, pattern, actual any) []mismatch {
+	p, ok := pattern.([]any)
+	if !ok {
+		return []mismatch{{pathOrRoot(path), fmt.Sprintf("%s pattern must be an array, got %s", m.name, toJSONString(pattern))}}
+	}
+	arr, ok := actual.([]any)
+	if !ok {
+		return []mismatch{{pathOrRoot(path), fmt.Sprintf("expected an array, got %s", toJSONString(actual))}}
+	}
+	next := 0
+	for _, elem := range arr {
+		if next < len(p) && len(m.match(path, p[next], elem)) == 0 {
+			next++
+		}
+	}
+	if next < len(p) {
+		return []mismatch{{
+			fmt.Sprintf("%s[%d]", path, next),
+			fmt.Sprintf("could not find pattern element %s in order in array %s", toJSONString(describePattern(p[next])), toJSONString(actual)),
+		}}
+	}
+	return nil
+}
+
+// diff returns the difference between the pattern and the actual value,
+// limited to the keys of partially matched objects.
+func (m patternMatcher) diff(actual any) string {
+	want, err := json.MarshalIndent(describePattern(m.pattern), "", "  ")
+	if err != nil {
+		return ""
+	}
+	if m.partialObjects {
+		actual = projectKeys(m.pattern, actual)
+	}
+	got, err := json.MarshalIndent(actual, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return cmp.Diff(string(want), string(got))
+}
+
+// toPattern converts a pattern to its generic JSON representation, keeping nested Matchers
+func toPattern(pattern any) (any, error) {
+	if m, ok := pattern.(Matcher); ok {
+		return m, nil
+	}
+	if pattern == nil {
+		return nil, nil
+	}
+	if raw, ok := pattern.(json.RawMessage); ok {
+		var result any
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return nil, fmt.Errorf("invalid JSON literal: %v", err)
+		}
+		return result, nil
+	}
+	v := reflect.ValueOf(pattern)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		result := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			elem, err := toPattern(iter.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+			result[iter.Key().String()] = elem
+		}
+		return result, nil
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			break // []byte is a base64 string in JSON
+		}
+		result := make([]any, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			elem, err := toPattern(v.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			result[i] = elem
+		}
+		return result, nil
+	}
+	return toJSONValue(pattern)
+}
+
+// describePattern replaces nested Matchers with their description
+func describePattern(pattern any) any {
+	switch p := pattern.(type) {
+	case Matcher:
+		return p.String()
+	case map[string]any:
+		result := make(map[string]any, len(p))
+		for k, v := range p {
+			result[k] = describePattern(v)
+		}
+		return result
+	case []any:
+		result := make([]any, len(p))
+		for i, v := range p {
+			result[i] = describePattern(v)
+		}
+		return result
+	default:
+		return pattern
+	}
+}
+
+// projectKeys removes object keys from actual which are not in the pattern
+func projectKeys(pattern, actual any) any {
+	switch p := pattern.(type) {
+	case map[string]any:
+		obj, ok := actual.(map[string]any)
+		if !ok {
+			return actual
+		}
+		result := make(map[string]any, len(p))
+		for k, v := range p {
+			if av, found := obj[k]; found {
+				result[k] = projectKeys(v, av)
+			}
+		}
+		return result
+	case []any:
+		arr, ok := actual.([]any)
+		if !ok || len(arr) != len(p) {
+			return actual
+		}
+		result := make([]any, len(arr))
+		for i := range arr {
+			result[i] = projectKeys(p[i], arr[i])
+		}
+		return result
+	default:
+		return actual
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "@"
+	}
+	return path
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
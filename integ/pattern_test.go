@@ -0,0 +1,105 @@
+package integ
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPattern_Success(t *testing.T) {
+	Assert(t, testObject, []Assertion{
+		{
+			Path: "request",
+			Matcher: ObjectLike(map[string]any{
+				"method": "GET",
+				"headers": map[string]any{
+					"host": map[string]any{"value": "www.example.com"},
+				},
+				"body": Absent(),
+				"uri":  AnyValue(),
+			}),
+		},
+		{
+			// JSON literal
+			Path:    "request.querystring",
+			Matcher: ObjectLike(`{"test": {"value": true}}`),
+		},
+		{
+			Path: "request.querystring.arg.multiValue",
+			Matcher: ArrayWith([]any{
+				map[string]any{"value": "val2"},
+			}),
+		},
+		{
+			Path:    "request.querystring.arg.multiValue[*].value",
+			Matcher: ArrayWith(`["val1", "val2"]`),
+		},
+		{
+			Path: "request.querystring.arg",
+			Matcher: Exact(map[string]any{
+				"multiValue": []any{
+					ObjectLike(map[string]any{"value": "val1"}),
+					map[string]any{"value": HasPrefix("val")},
+				},
+				"value": Regexp("^val1$"),
+			}),
+		},
+		{
+			Path:    "request.cookies.id",
+			Matcher: Exact(json.RawMessage(`{"value": "CookeIdValue"}`)),
+		},
+		{
+			Path:    "request.headers.authorization",
+			Matcher: Absent(),
+		},
+	})
+}
+
+func TestPattern_Failure(t *testing.T) {
+	tests := []struct {
+		description string
+		assertion   Assertion
+	}{
+		{"objectLike value", Assertion{Path: "request", Matcher: ObjectLike(map[string]any{"method": "POST"})}},
+		{"objectLike missing key", Assertion{Path: "request", Matcher: ObjectLike(map[string]any{"body": AnyValue()})}},
+		{"objectLike absent key", Assertion{Path: "request", Matcher: ObjectLike(map[string]any{"uri": Absent()})}},
+		{"objectLike not an object", Assertion{Path: "status", Matcher: ObjectLike(map[string]any{})}},
+		{"objectLike invalid JSON", Assertion{Path: "request", Matcher: ObjectLike(`{"method": `)}},
+		{"arrayWith order", Assertion{Path: "request.querystring.arg.multiValue[*].value", Matcher: ArrayWith([]string{"val2", "val1"})}},
+		{"arrayWith missing", Assertion{Path: "request.querystring.arg.multiValue[*].value", Matcher: ArrayWith([]string{"val3"})}},
+		{"arrayWith partial element", Assertion{Path: "request.headers.accept.multiValue", Matcher: ArrayWith([]any{map[string]any{}})}},
+		{"exact extra key", Assertion{Path: "request.querystring.arg", Matcher: Exact(map[string]any{"value": "val1"})}},
+		{"exact array length", Assertion{Path: "request.querystring.arg.multiValue", Matcher: Exact([]any{map[string]any{"value": "val1"}})}},
+		{"absent", Assertion{Path: "request.uri", Matcher: Absent()}},
+		{"anyValue", Assertion{Path: "request.body", Matcher: AnyValue()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			err := AssertE(testObject, []Assertion{tt.assertion})
+			assert.Error(t, err, "Expected error due to failed matcher %s", tt.assertion.Matcher)
+		})
+	}
+}
+
+func TestPattern_FailureReport(t *testing.T) {
+	err := ObjectLike(map[string]any{
+		"method": "POST",
+		"headers": map[string]any{
+			"host":   map[string]any{"value": "example.com"},
+			"accept": Absent(),
+		},
+		"body": AnyValue(),
+	}).Match(testObject["request"])
+	require.Error(t, err)
+	// each mismatching key is reported with its path
+	assert.Contains(t, err.Error(), `at 'body': missing key`)
+	assert.Contains(t, err.Error(), `at 'headers.accept': key should be absent`)
+	assert.Contains(t, err.Error(), `at 'headers.host.value': expected "example.com", got "www.example.com"`)
+	assert.Contains(t, err.Error(), `at 'method': expected "POST", got "GET"`)
+	// the diff only contains keys of the pattern
+	assert.Contains(t, err.Error(), "(-want +got)")
+	assert.NotContains(t, err.Error(), "querystring")
+}
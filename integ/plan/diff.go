@@ -0,0 +1,210 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	util "github.com/envtio/base/integ/aws"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// action symbols of the summary, as in the terraform plan output
+var actionSymbols = map[string]string{
+	ActionCreate:  "+",
+	ActionUpdate:  "~",
+	ActionDelete:  "-",
+	ActionReplace: "-/+",
+}
+
+// AttributeChange is a changed attribute of a resource change.
+type AttributeChange struct {
+	Path      string // Dotted attribute path, list elements by index, i.e. `tags.Name` or `environment.0.variables.KEY`
+	Before    any    // Value before the change, nil if the attribute is added
+	After     any    // Value after the change, nil if the attribute is removed or Unknown
+	Unknown   bool   // The value is known after apply
+	Sensitive bool   // The value is sensitive, Before and After are not printed
+}
+
+func (ac AttributeChange) String() string {
+	after := formatValue(ac.After)
+	if ac.Unknown {
+		after = "(known after apply)"
+	}
+	if ac.Sensitive {
+		return fmt.Sprintf("%s: (sensitive value)", ac.Path)
+	}
+	return fmt.Sprintf("%s: %s => %s", ac.Path, formatValue(ac.Before), after)
+}
+
+// formatValue formats a value as compact JSON
+func formatValue(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// AttributeChanges returns the changed leaf attributes of a resource change sorted by path.
+func AttributeChanges(rc *tfjson.ResourceChange) []AttributeChange {
+	if rc == nil || rc.Change == nil {
+		return nil
+	}
+	before := flatten(rc.Change.Before)
+	after := flatten(rc.Change.After)
+	unknown := flatten(rc.Change.AfterUnknown)
+	sensitive := flatten(rc.Change.BeforeSensitive)
+	for p, v := range flatten(rc.Change.AfterSensitive) {
+		sensitive[p] = v
+	}
+
+	paths := map[string]bool{}
+	for p := range before {
+		paths[p] = true
+	}
+	for p := range after {
+		paths[p] = true
+	}
+	for p, v := range unknown {
+		if v == true {
+			paths[p] = true
+		}
+	}
+	sortedPaths := make([]string, 0, len(paths))
+	for p := range paths {
+		sortedPaths = append(sortedPaths, p)
+	}
+	sort.Strings(sortedPaths)
+
+	var changes []AttributeChange
+	for _, p := range sortedPaths {
+		isUnknown := hasTrueParent(unknown, p)
+		if isUnknown && unknown[p] != true {
+			// reported with the unknown parent
+			continue
+		}
+		if !isUnknown && reflect.DeepEqual(before[p], after[p]) {
+			continue
+		}
+		changes = append(changes, AttributeChange{
+			Path:      p,
+			Before:    before[p],
+			After:     after[p],
+			Unknown:   isUnknown,
+			Sensitive: hasTrueParent(sensitive, p),
+		})
+	}
+	return changes
+}
+
+// flatten returns the leaf values of nested maps and lists by dotted path, empty maps and lists are leaves
+func flatten(value any) map[string]any {
+	leaves := map[string]any{}
+	flattenInto(leaves, "", value)
+	return leaves
+}
+
+func flattenInto(leaves map[string]any, prefix string, value any) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		if len(v) == 0 && prefix != "" {
+			leaves[prefix] = v
+		}
+		for key, child := range v {
+			flattenInto(leaves, join(key), child)
+		}
+	case []any:
+		if len(v) == 0 && prefix != "" {
+			leaves[prefix] = v
+		}
+		for i, child := range v {
+			flattenInto(leaves, join(strconv.Itoa(i)), child)
+		}
+	default:
+		if prefix != "" {
+			leaves[prefix] = v
+		}
+	}
+}
+
+// hasTrueParent returns true if the path or one of its parents is true, i.e. a whole block is unknown or sensitive
+func hasTrueParent(flags map[string]any, attributePath string) bool {
+	segments := strings.Split(attributePath, ".")
+	for i := len(segments); i > 0; i-- {
+		if flags[strings.Join(segments[:i], ".")] == true {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff returns the before and after diff of a resource change, see util.PrettyPrintResourceChange.
+func Diff(c Change) (string, error) {
+	return util.PrettyPrintResourceChange(c.Resource)
+}
+
+// Summary returns the number of changes per action and each change with its attribute changes.
+//
+//	Plan: 1 to create, 1 to update, 0 to delete, 0 to replace
+//	  + aws_sqs_queue.dlq
+//	  ~ aws_lambda_function.handler
+//	      timeout: 3 => 10
+func Summary(plan *terraform.PlanStruct) string {
+	return summarize("Plan", Changes(plan))
+}
+
+// summarize returns the summary of the changes
+func summarize(title string, changes []Change) string {
+	if len(changes) == 0 {
+		return title + ": no changes"
+	}
+	counts := map[string]int{}
+	for _, c := range changes {
+		counts[c.Action]++
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: %d to create, %d to update, %d to delete, %d to replace", title,
+		counts[ActionCreate], counts[ActionUpdate], counts[ActionDelete], counts[ActionReplace])
+	for _, c := range changes {
+		fmt.Fprintf(&sb, "\n  %s %s", actionSymbols[c.Action], c.Address)
+		if c.Action != ActionUpdate && c.Action != ActionReplace {
+			continue
+		}
+		for _, ac := range AttributeChanges(c.Resource) {
+			fmt.Fprintf(&sb, "\n      %s", ac)
+		}
+	}
+	return sb.String()
+}
+
+// Log logs the summary of the plan.
+func Log(t *testing.T, plan *terraform.PlanStruct) {
+	terratestLogger.Logf(t, "%s", Summary(plan))
+}
+
+// logDiffs logs the diff of each update and replacement
+func logDiffs(t *testing.T, changes []Change) {
+	for _, c := range changes {
+		if c.Action != ActionUpdate && c.Action != ActionReplace {
+			continue
+		}
+		diff, err := Diff(c)
+		if err != nil {
+			terratestLogger.Logf(t, "Failed to diff %s: %v", c.Address, err)
+			continue
+		}
+		terratestLogger.Logf(t, "Diff of %s: %s", c, diff)
+	}
+}
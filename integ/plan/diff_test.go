@@ -0,0 +1,118 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributeChanges(t *testing.T) {
+	rc := &tfjson.ResourceChange{
+		Address: "aws_lambda_function.handler",
+		Change: &tfjson.Change{
+			Actions: update,
+			Before: map[string]any{
+				"function_name": "test-handler",
+				"timeout":       float64(3),
+				"tags":          map[string]any{"EnvironmentName": "test"},
+				"tags_all":      map[string]any{"EnvironmentName": "test", "GridUUID": "1234"},
+				"layers":        []any{"arn:1"},
+				"environment":   []any{map[string]any{"variables": map[string]any{"SECRET": "old"}}},
+			},
+			After: map[string]any{
+				"function_name": "test-handler",
+				"timeout":       float64(10),
+				"tags":          map[string]any{"EnvironmentName": "renamed"},
+				"layers":        []any{"arn:1", "arn:2"},
+				"environment":   []any{map[string]any{"variables": map[string]any{"SECRET": "new"}}},
+			},
+			AfterUnknown: map[string]any{
+				"tags_all": true,
+				"arn":      false,
+			},
+			AfterSensitive: map[string]any{
+				"environment": []any{map[string]any{"variables": true}},
+			},
+		},
+	}
+	var got []string
+	for _, ac := range AttributeChanges(rc) {
+		got = append(got, ac.String())
+	}
+	assert.Equal(t, []string{
+		"environment.0.variables.SECRET: (sensitive value)",
+		"layers.1: null => \"arn:2\"",
+		"tags.EnvironmentName: \"test\" => \"renamed\"",
+		"tags_all: null => (known after apply)",
+		"timeout: 3 => 10",
+	}, got)
+}
+
+func TestAttributeChanges_Create(t *testing.T) {
+	changes := AttributeChanges(&tfjson.ResourceChange{
+		Change: &tfjson.Change{
+			Actions: create,
+			After:   map[string]any{"name": "dlq", "tags": map[string]any{}},
+		},
+	})
+	require.Len(t, changes, 2)
+	assert.Equal(t, "name: null => \"dlq\"", changes[0].String())
+	assert.Equal(t, "tags: null => {}", changes[1].String())
+	assert.Nil(t, AttributeChanges(&tfjson.ResourceChange{}))
+}
+
+func TestSummary(t *testing.T) {
+	plan := &terraform.PlanStruct{ResourceChangesMap: map[string]*tfjson.ResourceChange{
+		"aws_sqs_queue.dlq": {
+			Address: "aws_sqs_queue.dlq",
+			Type:    "aws_sqs_queue",
+			Change:  &tfjson.Change{Actions: create, After: map[string]any{"name": "dlq"}},
+		},
+		"aws_lambda_function.handler": {
+			Address: "aws_lambda_function.handler",
+			Type:    "aws_lambda_function",
+			Change: &tfjson.Change{
+				Actions: update,
+				Before:  map[string]any{"timeout": float64(3)},
+				After:   map[string]any{"timeout": float64(10)},
+			},
+		},
+		"aws_s3_bucket.site": {
+			Address: "aws_s3_bucket.site",
+			Type:    "aws_s3_bucket",
+			Change: &tfjson.Change{
+				Actions: replace,
+				Before:  map[string]any{"bucket": "test-site"},
+				After:   map[string]any{"bucket": "renamed-site"},
+			},
+		},
+		"aws_iam_role.old": {
+			Address: "aws_iam_role.old",
+			Type:    "aws_iam_role",
+			Change:  &tfjson.Change{Actions: del, Before: map[string]any{"name": "old"}},
+		},
+	}}
+	assert.Equal(t, `Plan: 1 to create, 1 to update, 1 to delete, 1 to replace
+  - aws_iam_role.old
+  ~ aws_lambda_function.handler
+      timeout: 3 => 10
+  -/+ aws_s3_bucket.site
+      bucket: "test-site" => "renamed-site"
+  + aws_sqs_queue.dlq`, Summary(plan))
+
+	assert.Equal(t, "Plan: no changes", Summary(testPlan(map[string]tfjson.Actions{"aws_sqs_queue.queue": noop})))
+}
+
+func TestDiff(t *testing.T) {
+	plan := testPlan(map[string]tfjson.Actions{"aws_lambda_function.handler": update})
+	rc := plan.ResourceChangesMap["aws_lambda_function.handler"]
+	rc.Change.Before = map[string]any{"timeout": float64(3)}
+	rc.Change.After = map[string]any{"timeout": float64(10)}
+	diff, err := Diff(Changes(plan)[0])
+	require.NoError(t, err)
+	assert.Contains(t, diff, "-")
+	assert.Contains(t, diff, "10")
+}
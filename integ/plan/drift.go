@@ -0,0 +1,73 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	util "github.com/envtio/base/integ/aws"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+// driftPlan is the part of the plan JSON representation not parsed by terraform.PlanStruct
+type driftPlan struct {
+	ResourceDrift []*tfjson.ResourceChange `json:"resource_drift"`
+}
+
+// ParseDrift returns the resources changed outside of Terraform from the JSON representation of a plan,
+// an update is a changed attribute and a delete is a resource removed outside of Terraform.
+func ParseDrift(planJSON []byte) ([]Change, error) {
+	var p driftPlan
+	if err := json.Unmarshal(planJSON, &p); err != nil {
+		return nil, fmt.Errorf("error parsing plan: %v", err)
+	}
+	return changesOf(p.ResourceDrift), nil
+}
+
+// DriftE runs a refresh-only plan of the deployed Terraform working dir and returns the resources changed outside of Terraform.
+func DriftE(t *testing.T, terraformOptions *terraform.Options) ([]Change, error) {
+	options, err := terraformOptions.Clone()
+	if err != nil {
+		return nil, err
+	}
+	options.Logger = logger.Discard
+	planFile, err := os.CreateTemp("", "terratest-drift-plan-")
+	if err != nil {
+		return nil, err
+	}
+	planFile.Close()
+	defer os.Remove(planFile.Name())
+	options.PlanFilePath = planFile.Name()
+
+	if _, err := terraform.RunTerraformCommandE(t, options, driftArgs(options)...); err != nil {
+		return nil, err
+	}
+	planJSON, err := terraform.ShowE(t, options)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDrift([]byte(planJSON))
+}
+
+// driftArgs returns the arguments of the refresh-only plan, locking is set by the options
+func driftArgs(options *terraform.Options) []string {
+	return terraform.FormatArgs(options, "plan", "-input=false", "-refresh-only")
+}
+
+// CheckDrift runs a refresh-only plan with the Terraform Options saved by the deploy stage and fails the test
+// if any resource changed outside of Terraform, logging the changed attributes and the diff of each resource.
+func CheckDrift(t *testing.T, tfWorkingDir string) []Change {
+	terraformOptions := util.LoadTerraformOptions(t, tfWorkingDir)
+	drift, err := DriftE(t, terraformOptions)
+	require.NoError(t, err)
+	terratestLogger.Logf(t, "%s", summarize("Drift", drift))
+	if len(drift) > 0 {
+		logDiffs(t, drift)
+		t.Fatalf("%d resource(s) of %s changed outside of Terraform", len(drift), tfWorkingDir)
+	}
+	return drift
+}
@@ -0,0 +1,72 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// refresh-only plan of a tagged queue and a deleted role, ref https://developer.hashicorp.com/terraform/internals/json-format#plan-representation
+const driftPlanJSON = `{
+  "format_version": "1.2",
+  "resource_drift": [
+    {
+      "address": "aws_sqs_queue.queue",
+      "mode": "managed",
+      "type": "aws_sqs_queue",
+      "name": "queue",
+      "change": {
+        "actions": ["update"],
+        "before": {"name": "test-queue", "tags": {"EnvironmentName": "test"}},
+        "after": {"name": "test-queue", "tags": {"EnvironmentName": "test", "Owner": "console"}},
+        "after_unknown": {},
+        "before_sensitive": {},
+        "after_sensitive": {}
+      }
+    },
+    {
+      "address": "aws_iam_role.role",
+      "mode": "managed",
+      "type": "aws_iam_role",
+      "name": "role",
+      "change": {
+        "actions": ["delete"],
+        "before": {"name": "test-role"},
+        "after": null
+      }
+    }
+  ],
+  "resource_changes": []
+}`
+
+func TestParseDrift(t *testing.T) {
+	drift, err := ParseDrift([]byte(driftPlanJSON))
+	require.NoError(t, err)
+	require.Len(t, drift, 2)
+	assert.Equal(t, "delete aws_iam_role.role", drift[0].String())
+	assert.Equal(t, "update aws_sqs_queue.queue", drift[1].String())
+	assert.Equal(t, `Drift: 0 to create, 1 to update, 1 to delete, 0 to replace
+  - aws_iam_role.role
+  ~ aws_sqs_queue.queue
+      tags.Owner: null => "console"`, summarize("Drift", drift))
+}
+
+func TestParseDrift_NoDrift(t *testing.T) {
+	drift, err := ParseDrift([]byte(`{"format_version": "1.2", "resource_changes": []}`))
+	require.NoError(t, err)
+	assert.Empty(t, drift)
+	assert.Equal(t, "Drift: no changes", summarize("Drift", drift))
+
+	_, err = ParseDrift([]byte(`{`))
+	assert.Error(t, err)
+}
+
+func TestDriftArgs(t *testing.T) {
+	args := driftArgs(&terraform.Options{Lock: true, LockTimeout: "10m"})
+	assert.Contains(t, args, "-lock=true")
+	assert.Contains(t, args, "-lock-timeout=10m")
+	assert.NotContains(t, args, "-lock=false")
+	assert.Contains(t, args, "-refresh-only")
+}
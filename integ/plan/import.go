@@ -0,0 +1,290 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	util "github.com/envtio/base/integ/aws"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/hashicorp/go-multierror"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// ImportFileName is the Terraform JSON configuration of the import blocks written to the working dir during the round-trip
+	ImportFileName = "import_roundtrip.tf.json"
+	// ImportIDsFileName is the test data file recording the imported resources of the round-trip
+	ImportIDsFileName = "ImportIDs.json"
+	// ImportStateBackupFileName is the test data file backing up the state during the round-trip, see RestoreImportStateE
+	ImportStateBackupFileName = "ImportStateBackup.tfstate"
+)
+
+// DefaultImportDeny lists resource types without import support, they are never removed from state
+var DefaultImportDeny = []string{
+	"aws_acm_certificate_validation",
+	"aws_lambda_invocation",
+	"null_resource",
+	"random_*",
+	"terraform_data",
+	"time_*",
+}
+
+// DefaultImportIDs builds the import ID of resource types not imported by their `id` attribute
+var DefaultImportIDs = map[string]ImportIDFunc{
+	"aws_iam_role_policy_attachment": ImportIDFromAttributes("/", "role", "policy_arn"),
+	"aws_lambda_permission":          ImportIDFromAttributes("/", "function_name", "statement_id"),
+	"aws_s3_object":                  ImportIDFromAttributes("/", "bucket", "key"),
+}
+
+// ImportIDFunc returns the import ID of a resource from its state attributes.
+type ImportIDFunc func(attributes map[string]any) (string, error)
+
+// ImportIDFromAttributes joins the string attributes with sep, i.e. `<role>/<policy_arn>`.
+func ImportIDFromAttributes(sep string, keys ...string) ImportIDFunc {
+	return func(attributes map[string]any) (string, error) {
+		values := make([]string, 0, len(keys))
+		for _, key := range keys {
+			value, ok := attributes[key].(string)
+			if !ok || value == "" {
+				return "", fmt.Errorf("missing attribute '%s'", key)
+			}
+			values = append(values, value)
+		}
+		return strings.Join(values, sep), nil
+	}
+}
+
+// ImportOptions selects the resources of an import round-trip by type, see ImportRoundTrip.
+type ImportOptions struct {
+	Allow []string                // Resource type globs imported, i.e. `aws_sqs_*`, defaults to all managed resources
+	Deny  []string                // Resource type globs never imported, in addition to DefaultImportDeny
+	IDs   map[string]ImportIDFunc // Import ID per resource type, over DefaultImportIDs. Defaults to the `id` attribute
+}
+
+// Import is a resource removed from state and imported by its ID.
+type Import struct {
+	Address string `json:"address"` // Resource address, i.e. `aws_sqs_queue.dlq`
+	Type    string `json:"type"`    // Resource type, i.e. `aws_sqs_queue`
+	ID      string `json:"id"`      // Import ID, i.e. the queue URL
+}
+
+// selects returns true if the resource type is allowed and not denied
+func (o ImportOptions) selects(resourceType string) (bool, error) {
+	denied, err := matchType(append(append([]string{}, DefaultImportDeny...), o.Deny...), resourceType)
+	if err != nil || denied {
+		return false, err
+	}
+	if len(o.Allow) == 0 {
+		return true, nil
+	}
+	return matchType(o.Allow, resourceType)
+}
+
+// importID returns the import ID of a resource
+func (o ImportOptions) importID(r *tfjson.StateResource) (string, error) {
+	idFunc, ok := o.IDs[r.Type]
+	if !ok {
+		idFunc, ok = DefaultImportIDs[r.Type]
+	}
+	if !ok {
+		idFunc = ImportIDFromAttributes("", "id")
+	}
+	id, err := idFunc(r.AttributeValues)
+	if err != nil {
+		return "", fmt.Errorf("no import ID of '%s': %v", r.Address, err)
+	}
+	return id, nil
+}
+
+// matchType returns true if a glob matches the resource type
+func matchType(globs []string, resourceType string) (bool, error) {
+	for _, glob := range globs {
+		matched, err := path.Match(glob, resourceType)
+		if err != nil {
+			return false, fmt.Errorf("invalid resource type glob '%s': %v", glob, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SelectImports returns the managed resources of the state selected by the options with their import IDs, sorted by address.
+func SelectImports(state *tfjson.State, opts ImportOptions) ([]Import, error) {
+	if state == nil || state.Values == nil || state.Values.RootModule == nil {
+		return nil, nil
+	}
+	var imports []Import
+	var combinedErr error
+	modules := []*tfjson.StateModule{state.Values.RootModule}
+	for len(modules) > 0 {
+		module := modules[0]
+		modules = append(modules[1:], module.ChildModules...)
+		for _, r := range module.Resources {
+			if r.Mode != tfjson.ManagedResourceMode {
+				continue
+			}
+			selected, err := opts.selects(r.Type)
+			if err != nil {
+				return nil, err
+			}
+			if !selected {
+				continue
+			}
+			id, err := opts.importID(r)
+			if err != nil {
+				combinedErr = multierror.Append(combinedErr, err)
+				continue
+			}
+			imports = append(imports, Import{Address: r.Address, Type: r.Type, ID: id})
+		}
+	}
+	sort.Slice(imports, func(i, j int) bool {
+		return imports[i].Address < imports[j].Address
+	})
+	return imports, combinedErr
+}
+
+// ImportBlocks returns the Terraform JSON configuration of the import blocks, ref https://opentofu.org/docs/language/import/
+func ImportBlocks(imports []Import) ([]byte, error) {
+	blocks := make([]map[string]string, 0, len(imports))
+	for _, i := range imports {
+		blocks = append(blocks, map[string]string{"to": i.Address, "id": i.ID})
+	}
+	return json.MarshalIndent(map[string]any{"import": blocks}, "", "  ")
+}
+
+// ImportRoundTripE removes the selected resources of the deployed Terraform working dir from state and plans
+// them with import blocks. The state is restored from a backup in the test data of the working dir afterwards, also
+// on errors. If the test is interrupted before, the cleanup restores the backup before destroying, see
+// RestoreImportStateE. Returns the imported resources and the plan, which is empty if they import without a diff.
+func ImportRoundTripE(t *testing.T, terraformOptions *terraform.Options, opts ImportOptions) (imports []Import, plan *terraform.PlanStruct, err error) {
+	// state and show output are not logged, they include sensitive values
+	quiet, err := terraformOptions.Clone()
+	if err != nil {
+		return nil, nil, err
+	}
+	quiet.Logger = logger.Discard
+	quiet.PlanFilePath = ""
+
+	stateJSON, err := terraform.ShowE(t, quiet)
+	if err != nil {
+		return nil, nil, err
+	}
+	var state tfjson.State
+	if err := json.Unmarshal([]byte(stateJSON), &state); err != nil {
+		return nil, nil, fmt.Errorf("error parsing state: %v", err)
+	}
+	if imports, err = SelectImports(&state, opts); err != nil {
+		return nil, nil, err
+	}
+	if len(imports) == 0 {
+		return nil, nil, fmt.Errorf("no resources of '%s' selected for import", terraformOptions.TerraformDir)
+	}
+
+	pulled, err := terraform.RunTerraformCommandAndGetStdoutE(t, quiet, "state", "pull")
+	if err != nil {
+		return nil, nil, err
+	}
+	backup := formatImportStateBackupPath(terraformOptions.TerraformDir)
+	if err := os.MkdirAll(filepath.Dir(backup), 0755); err != nil {
+		return nil, nil, err
+	}
+	if err := os.WriteFile(backup, []byte(pulled), 0600); err != nil {
+		return nil, nil, err
+	}
+
+	args := []string{"state", "rm"}
+	for _, i := range imports {
+		args = append(args, i.Address)
+	}
+	defer func() {
+		if _, restoreErr := RestoreImportStateE(t, terraformOptions); restoreErr != nil {
+			err = multierror.Append(err, restoreErr)
+		}
+	}()
+	if _, err := terraform.RunTerraformCommandE(t, terraformOptions, args...); err != nil {
+		return imports, nil, err
+	}
+
+	blocks, err := ImportBlocks(imports)
+	if err != nil {
+		return imports, nil, err
+	}
+	importFile := filepath.Join(terraformOptions.TerraformDir, ImportFileName)
+	if err := os.WriteFile(importFile, blocks, 0644); err != nil {
+		return imports, nil, err
+	}
+	defer os.Remove(importFile)
+
+	planOptions, err := terraformOptions.Clone()
+	if err != nil {
+		return imports, nil, err
+	}
+	planFile, err := os.CreateTemp("", "terratest-import-plan-")
+	if err != nil {
+		return imports, nil, err
+	}
+	planFile.Close()
+	defer os.Remove(planFile.Name())
+	planOptions.PlanFilePath = planFile.Name()
+	if _, err := terraform.RunTerraformCommandE(t, planOptions, terraform.FormatArgs(planOptions, "plan", "-input=false")...); err != nil {
+		return imports, nil, err
+	}
+	planOptions.Logger = logger.Discard
+	plan, err = terraform.ShowWithStructE(t, planOptions)
+	return imports, plan, err
+}
+
+// formatImportStateBackupPath returns the path of the state backup in the test data of the working dir
+func formatImportStateBackupPath(tfWorkingDir string) string {
+	return test_structure.FormatTestDataPath(tfWorkingDir, ImportStateBackupFileName)
+}
+
+// RestoreImportStateE pushes the state backup of an unfinished import round-trip and removes it, so the resources
+// removed from state are destroyed. Returns false if there is no backup in the test data of the working dir.
+func RestoreImportStateE(t *testing.T, terraformOptions *terraform.Options) (bool, error) {
+	backup := formatImportStateBackupPath(terraformOptions.TerraformDir)
+	if _, err := os.Stat(backup); os.IsNotExist(err) {
+		return false, nil
+	}
+	// terraform runs in the working dir
+	absBackup, err := filepath.Abs(backup)
+	if err != nil {
+		return false, err
+	}
+	// the backup includes sensitive values
+	quiet, err := terraformOptions.Clone()
+	if err != nil {
+		return false, err
+	}
+	quiet.Logger = logger.Discard
+	if _, err := terraform.RunTerraformCommandE(t, quiet, "state", "push", "-force", absBackup); err != nil {
+		return false, fmt.Errorf("failed to restore state of '%s' from %s: %v", terraformOptions.TerraformDir, backup, err)
+	}
+	return true, os.Remove(backup)
+}
+
+// ImportRoundTrip runs the import round-trip with the Terraform Options saved by the deploy stage, records the
+// imported resources in the test data of the working dir and fails the test unless the plan is empty.
+func ImportRoundTrip(t *testing.T, tfWorkingDir string, opts ImportOptions) []Import {
+	terraformOptions := util.LoadTerraformOptions(t, tfWorkingDir)
+	imports, plan, err := ImportRoundTripE(t, terraformOptions, opts)
+	if imports != nil {
+		test_structure.SaveTestData(t, test_structure.FormatTestDataPath(tfWorkingDir, ImportIDsFileName), true, imports)
+	}
+	require.NoError(t, err)
+	terratestLogger.Logf(t, "Imported %d resource(s) of %s", len(imports), tfWorkingDir)
+	Check(t, plan, ExpectEmptyPlan())
+	return imports
+}
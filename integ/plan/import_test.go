@@ -0,0 +1,172 @@
+package plan
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// state of a queue with a lambda consumer, ref https://developer.hashicorp.com/terraform/internals/json-format#state-representation
+const importStateJSON = `{
+  "format_version": "1.0",
+  "values": {
+    "root_module": {
+      "resources": [
+        {
+          "address": "aws_sqs_queue.queue",
+          "mode": "managed",
+          "type": "aws_sqs_queue",
+          "name": "queue",
+          "values": {"id": "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue", "name": "test-queue"}
+        },
+        {
+          "address": "data.aws_iam_policy_document.assume",
+          "mode": "data",
+          "type": "aws_iam_policy_document",
+          "name": "assume",
+          "values": {"id": "123"}
+        },
+        {
+          "address": "terraform_data.trigger",
+          "mode": "managed",
+          "type": "terraform_data",
+          "name": "trigger",
+          "values": {"id": "abc"}
+        }
+      ],
+      "child_modules": [
+        {
+          "address": "module.consumer",
+          "resources": [
+            {
+              "address": "module.consumer.aws_lambda_permission.sqs",
+              "mode": "managed",
+              "type": "aws_lambda_permission",
+              "name": "sqs",
+              "values": {"id": "AllowSqs", "function_name": "consumer", "statement_id": "AllowSqs"}
+            },
+            {
+              "address": "module.consumer.aws_iam_role.role",
+              "mode": "managed",
+              "type": "aws_iam_role",
+              "name": "role",
+              "values": {"id": "consumer-role", "name": "consumer-role"}
+            }
+          ]
+        }
+      ]
+    }
+  }
+}`
+
+func parseState(t *testing.T) *tfjson.State {
+	var state tfjson.State
+	require.NoError(t, json.Unmarshal([]byte(importStateJSON), &state))
+	return &state
+}
+
+func TestSelectImports(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     ImportOptions
+		expected []Import
+	}{
+		{
+			name: "all managed resources except denied types",
+			expected: []Import{
+				{Address: "aws_sqs_queue.queue", Type: "aws_sqs_queue", ID: "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue"},
+				{Address: "module.consumer.aws_iam_role.role", Type: "aws_iam_role", ID: "consumer-role"},
+				{Address: "module.consumer.aws_lambda_permission.sqs", Type: "aws_lambda_permission", ID: "consumer/AllowSqs"},
+			},
+		},
+		{
+			name: "allow list",
+			opts: ImportOptions{Allow: []string{"aws_sqs_*"}},
+			expected: []Import{
+				{Address: "aws_sqs_queue.queue", Type: "aws_sqs_queue", ID: "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue"},
+			},
+		},
+		{
+			name: "deny list over allow list",
+			opts: ImportOptions{Allow: []string{"aws_*"}, Deny: []string{"aws_lambda_*", "aws_iam_*"}},
+			expected: []Import{
+				{Address: "aws_sqs_queue.queue", Type: "aws_sqs_queue", ID: "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue"},
+			},
+		},
+		{
+			name: "custom import ID",
+			opts: ImportOptions{
+				Allow: []string{"aws_iam_role"},
+				IDs:   map[string]ImportIDFunc{"aws_iam_role": ImportIDFromAttributes("", "name")},
+			},
+			expected: []Import{
+				{Address: "module.consumer.aws_iam_role.role", Type: "aws_iam_role", ID: "consumer-role"},
+			},
+		},
+		{
+			name: "nothing selected",
+			opts: ImportOptions{Allow: []string{"aws_s3_*"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			imports, err := SelectImports(parseState(t), tc.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, imports)
+		})
+	}
+}
+
+func TestSelectImports_Errors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     ImportOptions
+		expected string
+	}{
+		{
+			name:     "missing import ID attribute",
+			opts:     ImportOptions{IDs: map[string]ImportIDFunc{"aws_iam_role": ImportIDFromAttributes("/", "name", "path")}},
+			expected: "no import ID of 'module.consumer.aws_iam_role.role': missing attribute 'path'",
+		},
+		{
+			name:     "invalid glob",
+			opts:     ImportOptions{Allow: []string{"aws_["}},
+			expected: "invalid resource type glob 'aws_['",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := SelectImports(parseState(t), tc.opts)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expected)
+		})
+	}
+}
+
+func TestSelectImports_EmptyState(t *testing.T) {
+	imports, err := SelectImports(&tfjson.State{}, ImportOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, imports)
+}
+
+func TestImportBlocks(t *testing.T) {
+	blocks, err := ImportBlocks([]Import{
+		{Address: "aws_sqs_queue.queue", Type: "aws_sqs_queue", ID: "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue"},
+		{Address: "module.consumer.aws_lambda_permission.sqs", Type: "aws_lambda_permission", ID: "consumer/AllowSqs"},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"import": [
+		{"to": "aws_sqs_queue.queue", "id": "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue"},
+		{"to": "module.consumer.aws_lambda_permission.sqs", "id": "consumer/AllowSqs"}
+	]}`, string(blocks))
+}
+
+func TestRestoreImportStateE_NoBackup(t *testing.T) {
+	restored, err := RestoreImportStateE(t, &terraform.Options{TerraformDir: t.TempDir()})
+	require.NoError(t, err)
+	assert.False(t, restored)
+}
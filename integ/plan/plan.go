@@ -0,0 +1,282 @@
+// Package plan asserts the resource changes of a Terraform plan, i.e. a re-synthesized app planned against the
+// deployed app, with composable expectations and a human-readable summary.
+package plan
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	util "github.com/envtio/base/integ/aws"
+	"github.com/envtio/base/integ/redact"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/hashicorp/go-multierror"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+// terratestLogger masks sensitive values
+var terratestLogger = redact.Logger
+
+// Actions of a planned resource change
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionReplace = "replace"
+)
+
+// Change is a create, update, delete or replace resource change of a plan.
+type Change struct {
+	Action   string                 // One of ActionCreate, ActionUpdate, ActionDelete, ActionReplace
+	Address  string                 // Resource address, i.e. `aws_sqs_queue.dlq`
+	Type     string                 // Resource type, i.e. `aws_sqs_queue`
+	Resource *tfjson.ResourceChange // Raw resource change with the before and after values
+}
+
+func (c Change) String() string {
+	return c.Action + " " + c.Address
+}
+
+// Changes returns the create, update, delete and replace changes of a plan sorted by address, no-op and read changes are omitted.
+func Changes(plan *terraform.PlanStruct) []Change {
+	resourceChanges := make([]*tfjson.ResourceChange, 0, len(plan.ResourceChangesMap))
+	for _, rc := range plan.ResourceChangesMap {
+		resourceChanges = append(resourceChanges, rc)
+	}
+	return changesOf(resourceChanges)
+}
+
+// changesOf returns the create, update, delete and replace changes sorted by address
+func changesOf(resourceChanges []*tfjson.ResourceChange) []Change {
+	var changes []Change
+	for _, rc := range resourceChanges {
+		if action := changeAction(rc); action != "" {
+			changes = append(changes, Change{Action: action, Address: rc.Address, Type: rc.Type, Resource: rc})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Address < changes[j].Address
+	})
+	return changes
+}
+
+// changeAction returns the action of a resource change, or "" for no-op and read changes
+func changeAction(rc *tfjson.ResourceChange) string {
+	if rc.Change == nil {
+		return ""
+	}
+	actions := rc.Change.Actions
+	switch {
+	case actions.Replace():
+		return ActionReplace
+	case actions.Create():
+		return ActionCreate
+	case actions.Update():
+		return ActionUpdate
+	case actions.Delete():
+		return ActionDelete
+	}
+	return ""
+}
+
+// Expectation checks the changes of a plan, returning an error per unexpected change.
+type Expectation func(changes []Change) error
+
+// ExpectEmptyPlan expects no changes at all.
+func ExpectEmptyPlan() Expectation {
+	return func(changes []Change) error {
+		return eachChange(changes, func(c Change) error {
+			return fmt.Errorf("unexpected %s of '%s', expected an empty plan", c.Action, c.Address)
+		})
+	}
+}
+
+// ExpectNoReplace expects no resource to be destroyed and re-created.
+func ExpectNoReplace() Expectation {
+	return func(changes []Change) error {
+		return eachChange(changes, func(c Change) error {
+			if c.Action == ActionReplace {
+				return fmt.Errorf("unexpected replace of '%s'", c.Address)
+			}
+			return nil
+		})
+	}
+}
+
+// ExpectNoDelete expects no resource to be destroyed, including replacements.
+func ExpectNoDelete() Expectation {
+	return func(changes []Change) error {
+		return eachChange(changes, func(c Change) error {
+			if c.Action == ActionDelete || c.Action == ActionReplace {
+				return fmt.Errorf("unexpected %s of '%s'", c.Action, c.Address)
+			}
+			return nil
+		})
+	}
+}
+
+// ExpectCreateCount expects exactly count resources to be created, replacements are not counted.
+func ExpectCreateCount(count int) Expectation {
+	return func(changes []Change) error {
+		created := 0
+		for _, c := range changes {
+			if c.Action == ActionCreate {
+				created++
+			}
+		}
+		if created != count {
+			return fmt.Errorf("expected %d resource(s) to be created, got %d", count, created)
+		}
+		return nil
+	}
+}
+
+// ExpectOnlyUpdates expects only in-place updates of resources matching the globs, see ChangeSet.
+func ExpectOnlyUpdates(globs ...string) Expectation {
+	return ExpectOnly(ChangeSet{Update: globs})
+}
+
+// ExpectOnlyAttributes expects in-place updates to change only attributes matching the globs, i.e. `tags`,
+// `tags.*` or `environment.0.variables.*`. A glob also matches all nested attributes of the matched path.
+// Creates, deletes and replacements are not checked.
+func ExpectOnlyAttributes(globs ...string) Expectation {
+	return func(changes []Change) error {
+		return eachChange(changes, func(c Change) error {
+			if c.Action != ActionUpdate {
+				return nil
+			}
+			var unexpected []string
+			for _, ac := range AttributeChanges(c.Resource) {
+				matched, err := matchAttribute(globs, ac.Path)
+				if err != nil {
+					return err
+				}
+				if !matched {
+					unexpected = append(unexpected, ac.Path)
+				}
+			}
+			if len(unexpected) > 0 {
+				return fmt.Errorf("unexpected update of '%s' attribute(s) %s", c.Address, strings.Join(unexpected, ", "))
+			}
+			return nil
+		})
+	}
+}
+
+// matchAttribute returns true if a glob matches the attribute path or one of its parents
+func matchAttribute(globs []string, attributePath string) (bool, error) {
+	segments := strings.Split(attributePath, ".")
+	for _, glob := range globs {
+		for i := len(segments); i > 0; i-- {
+			matched, err := path.Match(strings.ReplaceAll(glob, ".", "/"), strings.Join(segments[:i], "/"))
+			if err != nil {
+				return false, fmt.Errorf("invalid attribute glob '%s': %v", glob, err)
+			}
+			if matched {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// ChangeSet is the blast radius allowed for a plan. Each action lists globs matched against
+// the resource address or type, i.e. `aws_sqs_queue.*`, `aws_lambda_event_source_mapping` or `module.queue.*`.
+type ChangeSet struct {
+	Create  []string // Resources allowed to be created
+	Update  []string // Resources allowed to be updated in-place
+	Delete  []string // Resources allowed to be destroyed
+	Replace []string // Resources allowed to be destroyed and re-created
+}
+
+// ExpectOnly expects only the changes of the change set, any other change is unexpected.
+func ExpectOnly(cs ChangeSet) Expectation {
+	return func(changes []Change) error {
+		return eachChange(changes, func(c Change) error {
+			allowed, err := cs.Allows(c)
+			if err != nil {
+				return err
+			}
+			if !allowed {
+				return fmt.Errorf("unexpected %s of '%s'", c.Action, c.Address)
+			}
+			return nil
+		})
+	}
+}
+
+// globs returns the allowed globs of an action
+func (cs ChangeSet) globs(action string) []string {
+	switch action {
+	case ActionCreate:
+		return cs.Create
+	case ActionUpdate:
+		return cs.Update
+	case ActionDelete:
+		return cs.Delete
+	case ActionReplace:
+		return cs.Replace
+	}
+	return nil
+}
+
+// Allows returns true if the change matches a glob of its action.
+func (cs ChangeSet) Allows(c Change) (bool, error) {
+	for _, glob := range cs.globs(c.Action) {
+		for _, name := range []string{c.Address, c.Type} {
+			matched, err := path.Match(glob, name)
+			if err != nil {
+				return false, fmt.Errorf("invalid %s glob '%s': %v", c.Action, glob, err)
+			}
+			if matched {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// eachChange combines the errors of check for all changes
+func eachChange(changes []Change, check func(c Change) error) error {
+	var combinedErr error
+	for _, c := range changes {
+		if err := check(c); err != nil {
+			combinedErr = multierror.Append(combinedErr, err)
+		}
+	}
+	return combinedErr
+}
+
+// CheckE returns the combined errors of all expectations.
+func CheckE(plan *terraform.PlanStruct, expectations ...Expectation) error {
+	changes := Changes(plan)
+	var combinedErr error
+	for _, expect := range expectations {
+		if err := expect(changes); err != nil {
+			combinedErr = multierror.Append(combinedErr, err)
+		}
+	}
+	return combinedErr
+}
+
+// Check logs the summary of the plan and fails the test if an expectation is not met,
+// logging the full diff of all updates and replacements.
+func Check(t *testing.T, plan *terraform.PlanStruct, expectations ...Expectation) {
+	Log(t, plan)
+	if err := CheckE(plan, expectations...); err != nil {
+		logDiffs(t, Changes(plan))
+		require.NoError(t, err)
+	}
+}
+
+// Replan plans the Terraform working dir with the Terraform Options saved by the deploy stage,
+// logs the summary of the plan and fails the test if an expectation is not met.
+func Replan(t *testing.T, tfWorkingDir string, expectations ...Expectation) *terraform.PlanStruct {
+	terraformOptions := util.LoadTerraformOptions(t, tfWorkingDir)
+	plan := terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+	Check(t, plan, expectations...)
+	return plan
+}
@@ -0,0 +1,207 @@
+package plan
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPlan returns a plan with the resource changes, the type is the second to last segment of the address
+func testPlan(changes map[string]tfjson.Actions) *terraform.PlanStruct {
+	plan := &terraform.PlanStruct{ResourceChangesMap: map[string]*tfjson.ResourceChange{}}
+	for address, actions := range changes {
+		segments := strings.Split(address, ".")
+		plan.ResourceChangesMap[address] = &tfjson.ResourceChange{
+			Address: address,
+			Type:    segments[len(segments)-2],
+			Change:  &tfjson.Change{Actions: actions},
+		}
+	}
+	return plan
+}
+
+var (
+	create  = tfjson.Actions{tfjson.ActionCreate}
+	update  = tfjson.Actions{tfjson.ActionUpdate}
+	del     = tfjson.Actions{tfjson.ActionDelete}
+	replace = tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate}
+	noop    = tfjson.Actions{tfjson.ActionNoop}
+)
+
+func TestChanges(t *testing.T) {
+	plan := testPlan(map[string]tfjson.Actions{
+		"aws_sqs_queue.dlq":                     create,
+		"aws_lambda_function.handler":           update,
+		"aws_iam_role.old":                      del,
+		"aws_s3_bucket.site":                    replace,
+		"aws_cloudwatch_log_group.logs":         {tfjson.ActionCreate, tfjson.ActionDelete},
+		"aws_lambda_event_source_mapping.queue": noop,
+		"data.aws_caller_identity.current":      {tfjson.ActionRead},
+	})
+	var got []string
+	for _, c := range Changes(plan) {
+		assert.Same(t, plan.ResourceChangesMap[c.Address], c.Resource)
+		got = append(got, c.String()+" ("+c.Type+")")
+	}
+	assert.Equal(t, []string{
+		"replace aws_cloudwatch_log_group.logs (aws_cloudwatch_log_group)",
+		"delete aws_iam_role.old (aws_iam_role)",
+		"update aws_lambda_function.handler (aws_lambda_function)",
+		"replace aws_s3_bucket.site (aws_s3_bucket)",
+		"create aws_sqs_queue.dlq (aws_sqs_queue)",
+	}, got)
+}
+
+func TestExpectations(t *testing.T) {
+	plan := testPlan(map[string]tfjson.Actions{
+		"aws_sqs_queue.dlq":           create,
+		"aws_sqs_queue.queue":         update,
+		"aws_iam_role.old":            del,
+		"aws_s3_bucket.site":          replace,
+		"aws_lambda_function.handler": noop,
+	})
+	testCases := []struct {
+		name   string
+		expect Expectation
+		errors []string
+	}{
+		{"empty plan", ExpectEmptyPlan(), []string{
+			"unexpected create of 'aws_sqs_queue.dlq', expected an empty plan",
+			"unexpected update of 'aws_sqs_queue.queue', expected an empty plan",
+			"unexpected delete of 'aws_iam_role.old', expected an empty plan",
+			"unexpected replace of 'aws_s3_bucket.site', expected an empty plan",
+		}},
+		{"no replace", ExpectNoReplace(), []string{
+			"unexpected replace of 'aws_s3_bucket.site'",
+		}},
+		{"no delete", ExpectNoDelete(), []string{
+			"unexpected delete of 'aws_iam_role.old'",
+			"unexpected replace of 'aws_s3_bucket.site'",
+		}},
+		{"create count", ExpectCreateCount(1), nil},
+		{"wrong create count", ExpectCreateCount(2), []string{
+			"expected 2 resource(s) to be created, got 1",
+		}},
+		{"only updates", ExpectOnlyUpdates("aws_sqs_queue"), []string{
+			"unexpected create of 'aws_sqs_queue.dlq'",
+			"unexpected delete of 'aws_iam_role.old'",
+			"unexpected replace of 'aws_s3_bucket.site'",
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckE(plan, tc.expect)
+			if len(tc.errors) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, msg := range tc.errors {
+				assert.Contains(t, err.Error(), msg)
+			}
+			assert.NotContains(t, err.Error(), "aws_lambda_function.handler")
+		})
+	}
+}
+
+func TestCheckE_EmptyPlan(t *testing.T) {
+	plan := testPlan(map[string]tfjson.Actions{
+		"aws_sqs_queue.queue": noop,
+	})
+	assert.NoError(t, CheckE(plan, ExpectEmptyPlan(), ExpectNoReplace(), ExpectCreateCount(0), ExpectOnly(ChangeSet{})))
+}
+
+func TestExpectOnly(t *testing.T) {
+	addDlq := ChangeSet{
+		Create: []string{"aws_sqs_queue.*"},
+		Update: []string{"aws_lambda_event_source_mapping"},
+	}
+	testCases := []struct {
+		name    string
+		expect  ChangeSet
+		changes map[string]tfjson.Actions
+		errors  []string
+	}{
+		{
+			name:   "allowed by address and type glob",
+			expect: addDlq,
+			changes: map[string]tfjson.Actions{
+				"aws_sqs_queue.dlq":                     create,
+				"aws_lambda_event_source_mapping.queue": update,
+			},
+		},
+		{
+			name:   "action not allowed",
+			expect: addDlq,
+			changes: map[string]tfjson.Actions{
+				"aws_sqs_queue.dlq":   create,
+				"aws_sqs_queue.queue": replace,
+				"aws_iam_role.role":   update,
+			},
+			errors: []string{
+				"unexpected update of 'aws_iam_role.role'",
+				"unexpected replace of 'aws_sqs_queue.queue'",
+			},
+		},
+		{
+			name:   "module address",
+			expect: ChangeSet{Replace: []string{"module.queue.*"}},
+			changes: map[string]tfjson.Actions{
+				"module.queue.aws_sqs_queue.dlq": {tfjson.ActionCreate, tfjson.ActionDelete},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckE(testPlan(tc.changes), ExpectOnly(tc.expect))
+			if len(tc.errors) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, msg := range tc.errors {
+				assert.Contains(t, err.Error(), msg)
+			}
+			assert.NotContains(t, err.Error(), "aws_sqs_queue.dlq")
+		})
+	}
+}
+
+func TestExpectOnly_InvalidGlob(t *testing.T) {
+	err := CheckE(testPlan(map[string]tfjson.Actions{
+		"aws_sqs_queue.dlq": create,
+	}), ExpectOnly(ChangeSet{Create: []string{"aws_sqs_queue.["}}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid create glob 'aws_sqs_queue.['")
+}
+
+func TestExpectOnlyAttributes(t *testing.T) {
+	plan := &terraform.PlanStruct{ResourceChangesMap: map[string]*tfjson.ResourceChange{
+		"aws_lambda_function.handler": {
+			Address: "aws_lambda_function.handler",
+			Type:    "aws_lambda_function",
+			Change: &tfjson.Change{
+				Actions: update,
+				Before: map[string]any{
+					"timeout":     float64(3),
+					"tags":        map[string]any{"EnvironmentName": "test"},
+					"environment": []any{map[string]any{"variables": map[string]any{"LOG_LEVEL": "info"}}},
+				},
+				After: map[string]any{
+					"timeout":     float64(10),
+					"tags":        map[string]any{"EnvironmentName": "renamed"},
+					"environment": []any{map[string]any{"variables": map[string]any{"LOG_LEVEL": "debug"}}},
+				},
+			},
+		},
+	}}
+	assert.NoError(t, CheckE(plan, ExpectOnlyAttributes("timeout", "tags", "environment.*.variables.*")))
+
+	err := CheckE(plan, ExpectOnlyAttributes("tags.*"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected update of 'aws_lambda_function.handler' attribute(s) environment.0.variables.LOG_LEVEL, timeout")
+}
@@ -0,0 +1,161 @@
+package integ
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type quantifierKind int
+
+const (
+	quantifierDefault quantifierKind = iota
+	quantifierAny
+	quantifierAll
+	quantifierNone
+	quantifierExactly
+	quantifierAtLeast
+)
+
+// Quantifier defines how many elements of an array must match the assertion Matcher.
+//
+// The zero value matches the value as a whole, except for ExpectedRegexp which matches if any element matches.
+type Quantifier struct {
+	kind quantifierKind
+	n    int
+}
+
+var (
+	// Any requires at least one element to match
+	Any = Quantifier{kind: quantifierAny}
+	// All requires every element to match, an empty array matches
+	All = Quantifier{kind: quantifierAll}
+	// None requires no element to match
+	None = Quantifier{kind: quantifierNone}
+)
+
+// Exactly requires exactly n elements to match.
+func Exactly(n int) Quantifier {
+	return Quantifier{kind: quantifierExactly, n: n}
+}
+
+// AtLeast requires n or more elements to match.
+func AtLeast(n int) Quantifier {
+	return Quantifier{kind: quantifierAtLeast, n: n}
+}
+
+var quantifierRegexp = regexp.MustCompile(`^(exactly|atLeast)\((\d+)\)$`)
+
+// ParseQuantifier parses the string representation of a Quantifier: any, all, none, exactly(n) or atLeast(n).
+func ParseQuantifier(s string) (Quantifier, error) {
+	switch s {
+	case "any":
+		return Any, nil
+	case "all":
+		return All, nil
+	case "none":
+		return None, nil
+	}
+	m := quantifierRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return Quantifier{}, fmt.Errorf("invalid quantifier '%s', expected one of any, all, none, exactly(n) or atLeast(n)", s)
+	}
+	n, _ := strconv.Atoi(m[2])
+	if m[1] == "exactly" {
+		return Exactly(n), nil
+	}
+	return AtLeast(n), nil
+}
+
+func (q Quantifier) String() string {
+	switch q.kind {
+	case quantifierAny:
+		return "any"
+	case quantifierAll:
+		return "all"
+	case quantifierNone:
+		return "none"
+	case quantifierExactly:
+		return fmt.Sprintf("exactly(%d)", q.n)
+	case quantifierAtLeast:
+		return fmt.Sprintf("atLeast(%d)", q.n)
+	default:
+		return ""
+	}
+}
+
+// IsZero returns true for the zero value Quantifier
+func (q Quantifier) IsZero() bool {
+	return q.kind == quantifierDefault
+}
+
+// elementResult is the result of matching a single array element
+type elementResult struct {
+	index int
+	value any
+	err   error
+}
+
+// Match matches each element of the array value and checks the number of matching elements.
+// Failures list the offending elements by index.
+func (q Quantifier) Match(value any, m Matcher) error {
+	arr, ok := value.([]any)
+	if !ok {
+		return fmt.Errorf("quantifier %s expects an array, got %s", q, toJSONString(value))
+	}
+	var matched, unmatched []elementResult
+	for i, elem := range arr {
+		if err := m.Match(elem); err != nil {
+			unmatched = append(unmatched, elementResult{i, elem, err})
+		} else {
+			matched = append(matched, elementResult{i, elem, nil})
+		}
+	}
+
+	var offending []elementResult
+	switch q.kind {
+	case quantifierAny:
+		if len(matched) > 0 {
+			return nil
+		}
+		offending = unmatched
+	case quantifierAll:
+		if len(unmatched) == 0 {
+			return nil
+		}
+		offending = unmatched
+	case quantifierNone:
+		if len(matched) == 0 {
+			return nil
+		}
+		offending = matched
+	case quantifierExactly:
+		if len(matched) == q.n {
+			return nil
+		}
+		if len(matched) > q.n {
+			offending = matched
+		} else {
+			offending = unmatched
+		}
+	case quantifierAtLeast:
+		if len(matched) >= q.n {
+			return nil
+		}
+		offending = unmatched
+	default:
+		return m.Match(value)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "expected %s of %d element(s) to match %s, got %d", q, len(arr), m, len(matched))
+	for _, r := range offending {
+		if r.err != nil {
+			fmt.Fprintf(&sb, "\n  [%d] %s", r.index, r.err)
+		} else {
+			fmt.Fprintf(&sb, "\n  [%d] %s matches", r.index, toJSONString(r.value))
+		}
+	}
+	return fmt.Errorf("%s", sb.String())
+}
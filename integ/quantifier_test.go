@@ -0,0 +1,139 @@
+package integ
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuantifier_Success(t *testing.T) {
+	Assert(t, testObject, []Assertion{
+		{
+			Path:           "request.headers.accept.multiValue[].value",
+			Quantifier:     All,
+			ExpectedRegexp: strPtr("^[a-z/+]+$"),
+		},
+		{
+			Path:       "request.querystring.arg.multiValue[].value",
+			Quantifier: Any,
+			Matcher:    Equals("val2"),
+		},
+		{
+			Path:       "request.querystring.arg.multiValue[].value",
+			Quantifier: None,
+			Matcher:    Equals("val3"),
+		},
+		{
+			Path:           "request.querystring.arg.multiValue[].value",
+			Quantifier:     Exactly(2),
+			ExpectedRegexp: strPtr("^val"),
+		},
+		{
+			Path:           "keys(request.headers)",
+			Quantifier:     AtLeast(1),
+			ExpectedRegexp: strPtr("^host$"),
+		},
+		{
+			Path:       "request.cookies.*.value",
+			Quantifier: Exactly(1),
+			Matcher:    IsType(TypeBoolean),
+		},
+	})
+}
+
+func TestQuantifier_Failure(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		assertion     Assertion
+		expectedError string
+	}{
+		{
+			name: "all",
+			assertion: Assertion{
+				Path:           "request.querystring.arg.multiValue[].value",
+				Quantifier:     All,
+				ExpectedRegexp: strPtr("1$"),
+			},
+			expectedError: "expected all of 2 element(s) to match regexp(1$), got 1\n  [1] value 'val2' does not match regexp '1$'",
+		},
+		{
+			name: "none",
+			assertion: Assertion{
+				Path:           "request.querystring.arg.multiValue[].value",
+				Quantifier:     None,
+				ExpectedRegexp: strPtr("2$"),
+			},
+			expectedError: "expected none of 2 element(s) to match regexp(2$), got 1\n  [1] \"val2\" matches",
+		},
+		{
+			name: "any",
+			assertion: Assertion{
+				Path:       "request.querystring.arg.multiValue[].value",
+				Quantifier: Any,
+				Matcher:    HasPrefix("x"),
+			},
+			expectedError: "expected any of 2 element(s) to match prefix(x), got 0\n  [0]",
+		},
+		{
+			name: "exactly too many",
+			assertion: Assertion{
+				Path:           "request.querystring.arg.multiValue[].value",
+				Quantifier:     Exactly(1),
+				ExpectedRegexp: strPtr("^val"),
+			},
+			expectedError: "expected exactly(1) of 2 element(s) to match regexp(^val), got 2\n  [0] \"val1\" matches\n  [1] \"val2\" matches",
+		},
+		{
+			name: "at least",
+			assertion: Assertion{
+				Path:           "request.querystring.arg.multiValue[].value",
+				Quantifier:     AtLeast(2),
+				ExpectedRegexp: strPtr("1$"),
+			},
+			expectedError: "expected atLeast(2) of 2 element(s) to match regexp(1$), got 1\n  [1] value 'val2'",
+		},
+		{
+			name: "not an array",
+			assertion: Assertion{
+				Path:       "request.method",
+				Quantifier: All,
+				Matcher:    Equals("GET"),
+			},
+			expectedError: "quantifier all expects an array, got \"GET\"",
+		},
+		{
+			name: "no matcher",
+			assertion: Assertion{
+				Path:       "request.querystring.arg.multiValue[].value",
+				Quantifier: All,
+			},
+			expectedError: "requires ExpectedRegexp or Matcher",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := AssertE(testObject, []Assertion{tc.assertion})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expectedError)
+		})
+	}
+}
+
+func TestParseQuantifier(t *testing.T) {
+	for s, expected := range map[string]Quantifier{
+		"any":         Any,
+		"all":         All,
+		"none":        None,
+		"exactly(2)":  Exactly(2),
+		"atLeast(10)": AtLeast(10),
+	} {
+		q, err := ParseQuantifier(s)
+		require.NoError(t, err)
+		assert.Equal(t, expected, q)
+		assert.Equal(t, s, q.String())
+	}
+	for _, s := range []string{"", "every", "exactly", "atLeast(-1)"} {
+		_, err := ParseQuantifier(s)
+		assert.Error(t, err, "Expected error for quantifier '%s'", s)
+	}
+}
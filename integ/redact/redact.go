@@ -0,0 +1,179 @@
+// Package redact masks sensitive values, i.e. sensitive Terraform outputs, in test logs and reports.
+package redact
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	loggers "github.com/gruntwork-io/terratest/modules/logger"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+)
+
+const (
+	// Mask replaces sensitive values
+	Mask = "***"
+	// MinLength is the minimum length of a registered value, shorter values (i.e. `true` or `1`) would mask unrelated log output
+	MinLength = 4
+)
+
+var (
+	mu sync.RWMutex
+	// values counts the tests which registered a value, parallel tests may register the same value
+	values = map[string]int{}
+	// replacer is rebuilt when values change, longest values first so overlapping values are fully masked
+	replacer = strings.NewReplacer()
+)
+
+// Logger logs to stdout like the terratest default logger, with registered sensitive values masked.
+var Logger = loggers.New(redactingLogger{})
+
+type redactingLogger struct{}
+
+func (redactingLogger) Logf(t terratesting.TestingT, format string, args ...interface{}) {
+	// same call depth as the terratest default logger, so the prefix shows the caller of Logf
+	loggers.DoLog(t, 3, os.Stdout, String(fmt.Sprintf(format, args...)))
+}
+
+// Register registers sensitive values to mask until the test and its subtests complete,
+// values shorter than MinLength are ignored.
+func Register(t testing.TB, sensitiveValues ...string) {
+	var registered []string
+	for _, v := range sensitiveValues {
+		if len(v) >= MinLength {
+			registered = append(registered, v)
+		}
+	}
+	if len(registered) == 0 {
+		return
+	}
+	update(registered, 1)
+	t.Cleanup(func() {
+		update(registered, -1)
+	})
+}
+
+// RegisterValue registers all string and number leaves of a JSON value, i.e. a sensitive output object,
+// until the test and its subtests complete.
+func RegisterValue(t testing.TB, value any) {
+	var leaves []string
+	collectLeaves(value, &leaves)
+	Register(t, leaves...)
+}
+
+// update adds delta to the registration count of the values and rebuilds the replacer if a value was added or removed
+func update(registered []string, delta int) {
+	mu.Lock()
+	defer mu.Unlock()
+	changed := false
+	for _, v := range registered {
+		count := values[v] + delta
+		if count <= 0 {
+			delete(values, v)
+		} else {
+			values[v] = count
+		}
+		changed = changed || count == delta || count <= 0
+	}
+	if changed {
+		replacer = newReplacer()
+	}
+}
+
+// String returns s with all registered sensitive values masked.
+func String(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return replacer.Replace(s)
+}
+
+// Value returns a copy of a JSON value with the registered sensitive values masked in its string leaves,
+// number leaves equal to a registered value are replaced by Mask.
+func Value(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for k, elem := range v {
+			result[k] = Value(elem)
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for i, elem := range v {
+			result[i] = Value(elem)
+		}
+		return result
+	case string:
+		return String(v)
+	case float64:
+		mu.RLock()
+		defer mu.RUnlock()
+		if _, ok := values[fmt.Sprintf("%v", v)]; ok {
+			return Mask
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// MaskValue returns a copy of a JSON value with all string, number and boolean leaves masked, keeping the structure of objects and arrays.
+func MaskValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for k, elem := range v {
+			result[k] = MaskValue(elem)
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for i, elem := range v {
+			result[i] = MaskValue(elem)
+		}
+		return result
+	case nil:
+		return nil
+	default:
+		return Mask
+	}
+}
+
+func collectLeaves(value any, leaves *[]string) {
+	switch v := value.(type) {
+	case map[string]any:
+		for _, elem := range v {
+			collectLeaves(elem, leaves)
+		}
+	case []any:
+		for _, elem := range v {
+			collectLeaves(elem, leaves)
+		}
+	case string:
+		*leaves = append(*leaves, v)
+	case float64:
+		*leaves = append(*leaves, fmt.Sprintf("%v", v))
+	}
+}
+
+// newReplacer must be called with mu held
+func newReplacer() *strings.Replacer {
+	sorted := make([]string, 0, len(values))
+	for v := range values {
+		sorted = append(sorted, v)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if len(sorted[i]) != len(sorted[j]) {
+			return len(sorted[i]) > len(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+	oldnew := make([]string, 0, 2*len(sorted))
+	for _, v := range sorted {
+		oldnew = append(oldnew, v, Mask)
+	}
+	return strings.NewReplacer(oldnew...)
+}
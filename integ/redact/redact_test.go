@@ -0,0 +1,69 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact_String(t *testing.T) {
+	assert.Equal(t, "secret-key", String("secret-key"), "Expected no masking without registered values")
+
+	Register(t, "secret-key", "secret", "abc")
+	assert.Equal(t, "SECRET_KEY=*** and ***", String("SECRET_KEY=secret-key and secret"))
+	assert.Equal(t, "abc", String("abc"), "Expected values shorter than MinLength to be ignored")
+}
+
+func TestRedact_RegisterValue(t *testing.T) {
+	RegisterValue(t, map[string]any{
+		"username": "admin-user",
+		"password": "hunter22",
+		"port":     float64(5432),
+		"tls":      true,
+		"hosts":    []any{"db.internal.example.com"},
+	})
+	assert.Equal(t,
+		`{"username":"***","password":"***","port":***,"tls":true,"hosts":["***"]}`,
+		String(`{"username":"admin-user","password":"hunter22","port":5432,"tls":true,"hosts":["db.internal.example.com"]}`))
+}
+
+func TestRedact_MaskValue(t *testing.T) {
+	assert.Equal(t, map[string]any{
+		"password": Mask,
+		"port":     Mask,
+		"hosts":    []any{Mask},
+		"none":     nil,
+	}, MaskValue(map[string]any{
+		"password": "hunter22",
+		"port":     float64(5432),
+		"hosts":    []any{"db.internal.example.com"},
+		"none":     nil,
+	}))
+	assert.Equal(t, Mask, MaskValue("secret"))
+}
+
+func TestRedact_Register_Scope(t *testing.T) {
+	t.Run("first", func(t *testing.T) {
+		Register(t, "scoped-secret")
+		t.Run("second", func(t *testing.T) {
+			Register(t, "scoped-secret")
+		})
+		assert.Equal(t, "***", String("scoped-secret"), "Expected the value to stay registered by the first test")
+	})
+	assert.Equal(t, "scoped-secret", String("scoped-secret"), "Expected the value to be unregistered once all tests completed")
+}
+
+func TestRedact_Value(t *testing.T) {
+	RegisterValue(t, map[string]any{"password": "hunter22", "port": float64(5432)})
+	assert.Equal(t, map[string]any{
+		"url":   "postgres://admin:***@db:***",
+		"port":  Mask,
+		"other": float64(5433),
+		"hosts": []any{"***", true},
+	}, Value(map[string]any{
+		"url":   "postgres://admin:hunter22@db:5432",
+		"port":  float64(5432),
+		"other": float64(5433),
+		"hosts": []any{"hunter22", true},
+	}))
+}
@@ -0,0 +1,314 @@
+package integ
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/envtio/base/integ/redact"
+	"github.com/hashicorp/go-multierror"
+)
+
+const (
+	// DefaultReportDir is the directory assertion reports are written to, relative to the test working directory
+	DefaultReportDir = "test-reports"
+	// ReportDirEnvVar overrides DefaultReportDir
+	ReportDirEnvVar = "TEST_REPORTS_DIR"
+)
+
+// AssertionResult is the outcome of a single Assertion.
+type AssertionResult struct {
+	Path       string        `json:"path"`                 // JMESPath of the asserted value
+	Matcher    string        `json:"matcher,omitempty"`    // Description of the matcher, empty if only existence is asserted
+	Quantifier string        `json:"quantifier,omitempty"` // Quantifier applied to array elements, if any
+	Expected   any           `json:"expected,omitempty"`   // Expected value, regexp or pattern
+	Actual     any           `json:"actual,omitempty"`     // Value found at Path, nil if missing
+	Passed     bool          `json:"passed"`               // Whether the assertion passed
+	Error      string        `json:"error,omitempty"`      // Failure message if the assertion did not pass
+	Duration   time.Duration `json:"duration"`             // Time spent searching and matching
+	Source     string        `json:"source,omitempty"`     // Origin of the asserted input, i.e. a test event path
+}
+
+// expectedValuer is implemented by matchers reporting an expected value other than their description
+type expectedValuer interface {
+	expectedValue() any
+}
+
+// expectedValue returns the expected value reported for a matcher
+func expectedValue(m Matcher) any {
+	if m == nil {
+		return nil
+	}
+	if ev, ok := m.(expectedValuer); ok {
+		return ev.expectedValue()
+	}
+	return m.String()
+}
+
+// ResultsError combines the errors of all failed results, returns nil if all results passed.
+func ResultsError(results []AssertionResult) error {
+	var combinedErr error
+	for _, r := range results {
+		if !r.Passed {
+			combinedErr = multierror.Append(combinedErr, errors.New(r.Error))
+		}
+	}
+	return combinedErr
+}
+
+// LogResults logs a line per result with the test logger, failures include the actual value.
+func LogResults(t *testing.T, results []AssertionResult) {
+	for _, r := range results {
+		status := "PASS"
+		if !r.Passed {
+			status = "FAIL"
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%s %s", status, r.Path)
+		if r.Source != "" {
+			fmt.Fprintf(&sb, " (%s)", r.Source)
+		}
+		if r.Quantifier != "" {
+			fmt.Fprintf(&sb, " %s", r.Quantifier)
+		}
+		if r.Matcher != "" {
+			fmt.Fprintf(&sb, " %s", r.Matcher)
+		}
+		fmt.Fprintf(&sb, " in %s", r.Duration.Round(time.Microsecond))
+		if !r.Passed {
+			fmt.Fprintf(&sb, ": actual %s: %s", toJSONString(r.Actual), r.Error)
+		}
+		terratestLogger.Logf(t, "%s", sb.String())
+	}
+}
+
+// Reporter collects assertion results of a test and writes them as JUnit XML and JSON
+// to `<dir>/<test name>.xml` and `<dir>/<test name>.json` when the test completes.
+//
+// Reporter is safe for concurrent use by parallel subtests.
+type Reporter struct {
+	Dir string // Directory the reports are written to, defaults to $TEST_REPORTS_DIR or DefaultReportDir
+
+	t       *testing.T
+	start   time.Time
+	mu      sync.Mutex
+	results []AssertionResult
+}
+
+// NewReporter returns a Reporter for the test, the reports are written on test cleanup.
+func NewReporter(t *testing.T) *Reporter {
+	r := &Reporter{Dir: reportDir(), t: t, start: time.Now()}
+	t.Cleanup(func() {
+		if err := r.WriteE(); err != nil {
+			t.Errorf("failed to write assertion reports: %v", err)
+		}
+	})
+	return r
+}
+
+// reporters holds the Reporter of each running test, see ReporterFor
+var reporters = struct {
+	mu  sync.Mutex
+	byT map[*testing.T]*Reporter
+}{byT: map[*testing.T]*Reporter{}}
+
+// ReporterFor returns the Reporter of the test, created with NewReporter on first use.
+// Assert, AssertEventually, AssertConsistently and RunEventAssertions record their results with it.
+func ReporterFor(t *testing.T) *Reporter {
+	reporters.mu.Lock()
+	defer reporters.mu.Unlock()
+	if r, ok := reporters.byT[t]; ok {
+		return r
+	}
+	r := NewReporter(t)
+	reporters.byT[t] = r
+	// runs before the reports are written
+	t.Cleanup(func() {
+		reporters.mu.Lock()
+		defer reporters.mu.Unlock()
+		delete(reporters.byT, t)
+	})
+	return r
+}
+
+// reportDir returns $TEST_REPORTS_DIR or DefaultReportDir
+func reportDir() string {
+	if dir := os.Getenv(ReportDirEnvVar); dir != "" {
+		return dir
+	}
+	return DefaultReportDir
+}
+
+// Assert asserts the given input against the provided assertions and records the results.
+// Fails the test if any assertion fails.
+func (r *Reporter) Assert(t *testing.T, input any, assertions []Assertion) {
+	if err := r.AssertE(input, assertions); err != nil {
+		t.Errorf("failed assertions: %v", err)
+	}
+}
+
+// AssertE asserts the given input against the provided assertions, records the results
+// and returns an error if any assertion fails.
+func (r *Reporter) AssertE(input any, assertions []Assertion) error {
+	results, err := EvaluateE(input, assertions)
+	if err != nil {
+		return err
+	}
+	r.Record("", results...)
+	return ResultsError(results)
+}
+
+// Record adds results to the report, source (if not empty) overrides the Source of the results.
+//
+// Registered sensitive values are masked when recorded, the reports are written after the test
+// released its registrations (see redact.Register).
+func (r *Reporter) Record(source string, results ...AssertionResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, result := range results {
+		if source != "" {
+			result.Source = source
+		}
+		result.Expected = redact.Value(result.Expected)
+		result.Actual = redact.Value(result.Actual)
+		result.Error = redact.String(result.Error)
+		r.results = append(r.results, result)
+	}
+}
+
+// Results returns a copy of the recorded results.
+func (r *Reporter) Results() []AssertionResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]AssertionResult{}, r.results...)
+}
+
+// WriteE writes the JUnit XML and JSON reports, nothing is written if no results were recorded.
+func (r *Reporter) WriteE() error {
+	results := r.Results()
+	if len(results) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(r.Dir, 0755); err != nil {
+		return err
+	}
+	base := filepath.Join(r.Dir, reportFileName(r.t.Name()))
+
+	jsonReport, err := json.MarshalIndent(jsonReport{
+		Test:    r.t.Name(),
+		Start:   r.start,
+		Passed:  ResultsError(results) == nil,
+		Results: results,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling JSON report: %v", err)
+	}
+	// actual values may contain revealed sensitive outputs
+	if err := os.WriteFile(base+".json", []byte(redact.String(string(jsonReport))), 0644); err != nil {
+		return err
+	}
+
+	junitReport, err := xml.MarshalIndent(newJUnitSuites(r.t.Name(), r.start, results), "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling JUnit report: %v", err)
+	}
+	if err := os.WriteFile(base+".xml", []byte(xml.Header+redact.String(string(junitReport))), 0644); err != nil {
+		return err
+	}
+	terratestLogger.Logf(r.t, "Wrote assertion reports %s.{json,xml}", base)
+	return nil
+}
+
+var reportFileNameRegexp = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// reportFileName converts a test name (with subtests) to a file name
+func reportFileName(testName string) string {
+	return reportFileNameRegexp.ReplaceAllString(testName, "_")
+}
+
+type jsonReport struct {
+	Test    string            `json:"test"`
+	Start   time.Time         `json:"start"`
+	Passed  bool              `json:"passed"`
+	Results []AssertionResult `json:"results"`
+}
+
+// ref https://github.com/testmoapp/junitxml
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Content string `xml:",chardata"`
+}
+
+func newJUnitSuites(testName string, start time.Time, results []AssertionResult) junitTestSuites {
+	suite := junitTestSuite{
+		Name:      testName,
+		Tests:     len(results),
+		Timestamp: start.UTC().Format(time.RFC3339),
+	}
+	var total time.Duration
+	for _, r := range results {
+		total += r.Duration
+		name := r.Path
+		if r.Source != "" {
+			name = fmt.Sprintf("%s: %s", r.Source, r.Path)
+		}
+		tc := junitTestCase{
+			Name:      name,
+			ClassName: testName,
+			Time:      junitSeconds(r.Duration),
+		}
+		if !r.Passed {
+			suite.Failures++
+			tc.Failure = &junitFailure{
+				Message: r.Error,
+				Type:    "AssertionError",
+				Content: fmt.Sprintf("matcher: %s\nexpected: %s\nactual: %s", r.Matcher, toJSONString(r.Expected), toJSONString(r.Actual)),
+			}
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Time = junitSeconds(total)
+	return junitTestSuites{
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,150 @@
+package integ
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/envtio/base/integ/redact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluate(t *testing.T) {
+	results, err := EvaluateE(testObject, []Assertion{
+		{
+			Path:           "request.uri",
+			ExpectedRegexp: strPtr("index.html$"),
+		},
+		{
+			Path:    "status",
+			Matcher: Equals(404),
+		},
+		{
+			Path:       "request.querystring.arg.multiValue[].value",
+			Quantifier: All,
+			Matcher:    ObjectLike(map[string]any{"value": "val1"}),
+		},
+		{
+			Path:   "request.headers.host",
+			Exists: true,
+		},
+		{
+			Path:   "request.missing",
+			Exists: true,
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+
+	assert.True(t, results[0].Passed)
+	assert.Equal(t, "regexp(index.html$)", results[0].Matcher)
+	assert.Equal(t, "index.html$", results[0].Expected)
+	assert.Equal(t, "/index.html", results[0].Actual)
+	assert.Empty(t, results[0].Error)
+
+	assert.False(t, results[1].Passed)
+	assert.Equal(t, 404, results[1].Expected)
+	assert.Equal(t, float64(200), results[1].Actual)
+	assert.Contains(t, results[1].Error, "value '200' does not equal '404'")
+
+	assert.False(t, results[2].Passed)
+	assert.Equal(t, "all", results[2].Quantifier)
+	assert.Equal(t, map[string]any{"value": "val1"}, results[2].Expected)
+	assert.Equal(t, []any{"val1", "val2"}, results[2].Actual)
+
+	assert.True(t, results[3].Passed)
+	assert.Equal(t, "exists()", results[3].Matcher)
+
+	assert.False(t, results[4].Passed)
+	assert.Nil(t, results[4].Actual)
+	assert.Equal(t, "value at 'request.missing' is nil", results[4].Error)
+
+	err = ResultsError(results)
+	require.Error(t, err)
+	assert.Equal(t, err.Error(), AssertE(testObject, []Assertion{
+		{Path: "status", Matcher: Equals(404)},
+		{Path: "request.querystring.arg.multiValue[].value", Quantifier: All, Matcher: ObjectLike(map[string]any{"value": "val1"})},
+		{Path: "request.missing", Exists: true},
+	}).Error())
+	assert.NoError(t, ResultsError(results[:1]))
+}
+
+func TestReporter(t *testing.T) {
+	reportDir := t.TempDir()
+	t.Setenv(ReportDirEnvVar, reportDir)
+	var reporter *Reporter
+	t.Run("subtest", func(t *testing.T) {
+		reporter = NewReporter(t)
+		reporter.Assert(t, testObject, []Assertion{
+			{Path: "request.method", Matcher: Equals("GET")},
+		})
+		err := reporter.AssertE(testObject, []Assertion{
+			{Path: "status", Matcher: Between(400, 499)},
+		})
+		assert.Error(t, err)
+		// registered after the reporter, released before the reports are written
+		redact.Register(t, "reporter-secret")
+		reporter.Record("testevents/event.json", AssertionResult{Path: "request.uri", Actual: "/reporter-secret", Passed: true})
+		LogResults(t, reporter.Results())
+	})
+	require.NotNil(t, reporter)
+	require.Len(t, reporter.Results(), 3)
+	assert.Equal(t, "testevents/event.json", reporter.Results()[2].Source)
+
+	data, err := os.ReadFile(filepath.Join(reportDir, "TestReporter_subtest.json"))
+	require.NoError(t, err)
+	var jsonReport jsonReport
+	require.NoError(t, json.Unmarshal(data, &jsonReport))
+	assert.Equal(t, "TestReporter/subtest", jsonReport.Test)
+	assert.False(t, jsonReport.Passed)
+	assert.Len(t, jsonReport.Results, 3)
+	assert.Equal(t, "/***", jsonReport.Results[2].Actual, "Expected registered values to be masked when recorded")
+
+	data, err = os.ReadFile(filepath.Join(reportDir, "TestReporter_subtest.xml"))
+	require.NoError(t, err)
+	var junitReport junitTestSuites
+	require.NoError(t, xml.Unmarshal(data, &junitReport))
+	assert.Equal(t, 3, junitReport.Tests)
+	assert.Equal(t, 1, junitReport.Failures)
+	require.Len(t, junitReport.Suites, 1)
+	require.Len(t, junitReport.Suites[0].TestCases, 3)
+	assert.Equal(t, "testevents/event.json: request.uri", junitReport.Suites[0].TestCases[2].Name)
+	failure := junitReport.Suites[0].TestCases[1].Failure
+	require.NotNil(t, failure)
+	assert.Contains(t, failure.Message, "does not match between(400, 499)")
+	assert.Contains(t, failure.Content, "actual: 200")
+}
+
+func TestReporterFor(t *testing.T) {
+	reportDir := t.TempDir()
+	t.Setenv(ReportDirEnvVar, reportDir)
+	t.Run("subtest", func(t *testing.T) {
+		assert.Same(t, ReporterFor(t), ReporterFor(t))
+		Assert(t, testObject, []Assertion{
+			{Path: "request.method", Matcher: Equals("GET")},
+		})
+		AssertEventually(t, func() (any, error) {
+			return testObject, nil
+		}, []Assertion{
+			{Path: "status", Matcher: Equals(200)},
+		}, PollOptions{Timeout: time.Second})
+	})
+
+	data, err := os.ReadFile(filepath.Join(reportDir, "TestReporterFor_subtest.json"))
+	require.NoError(t, err)
+	var jsonReport jsonReport
+	require.NoError(t, json.Unmarshal(data, &jsonReport))
+	assert.True(t, jsonReport.Passed)
+	require.Len(t, jsonReport.Results, 2)
+	assert.Equal(t, "request.method", jsonReport.Results[0].Path)
+	assert.Equal(t, "status", jsonReport.Results[1].Path)
+}
+
+func TestReportFileName(t *testing.T) {
+	assert.Equal(t, "TestEdge_url-rewrite-spa_no-file-name", reportFileName("TestEdge/url-rewrite-spa/no-file-name"))
+	assert.Equal(t, "TestMatrix_region_us-east-1_", reportFileName("TestMatrix/region=us-east-1 "))
+}
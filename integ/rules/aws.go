@@ -0,0 +1,260 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DefaultRequiredTags are the tag keys expected on all taggable resources
+var DefaultRequiredTags = []string{"EnvironmentName", "GridUUID"}
+
+// Options configures DefaultRules.
+type Options struct {
+	RequiredTags       []string // Tag keys required on taggable resources, defaults to DefaultRequiredTags
+	AllowPublicBuckets bool     // Skip NoPublicBuckets, i.e. for static website apps
+}
+
+// DefaultRules returns the built-in rules. Missing tags are reported as warnings, as the library does not apply tags yet.
+func DefaultRules(opts Options) []Rule {
+	requiredTags := opts.RequiredTags
+	if len(requiredTags) == 0 {
+		requiredTags = DefaultRequiredTags
+	}
+	rules := []Rule{
+		RequiredTags(SeverityWarning, requiredTags...),
+		NoWildcardIAM(),
+		LambdaLogRetention(),
+	}
+	if !opts.AllowPublicBuckets {
+		rules = append(rules, NoPublicBuckets())
+	}
+	return rules
+}
+
+// RequiredTags reports taggable resources missing any of the tag keys, tags applied through provider `default_tags` are taken into account.
+func RequiredTags(severity Severity, keys ...string) Rule {
+	return Rule{
+		Name:     "required-tags",
+		Severity: severity,
+		Check: func(stack *Stack) []Violation {
+			defaultTags := stack.providerDefaultTags()
+			var violations []Violation
+			for _, r := range stack.Resources {
+				if r.Mode != "managed" || !isTaggable(r) {
+					continue
+				}
+				tags, _ := r.Config["tags"].(map[string]any)
+				var missing []string
+				for _, key := range keys {
+					_, ok := tags[key]
+					if !ok && !defaultTags[key] {
+						missing = append(missing, key)
+					}
+				}
+				if len(missing) > 0 {
+					violations = append(violations, Violation{
+						Resource: r,
+						Message:  fmt.Sprintf("missing required tag(s) %s", strings.Join(missing, ", ")),
+					})
+				}
+			}
+			return violations
+		},
+	}
+}
+
+// NoWildcardIAM reports Allow statements granting all actions (`*`) on all resources (`*`),
+// in `aws_iam_policy_document` data sources and inline JSON policies.
+func NoWildcardIAM() Rule {
+	return Rule{
+		Name:     "no-wildcard-iam",
+		Severity: SeverityError,
+		Check: func(stack *Stack) []Violation {
+			var violations []Violation
+			for _, r := range stack.Resources {
+				var statements []map[string]any
+				if r.Mode == "data" && r.Type == "aws_iam_policy_document" {
+					statements = toObjects(r.Config["statement"])
+				} else if policy, ok := r.Config["policy"].(string); ok {
+					statements = jsonPolicyStatements(policy)
+				}
+				for i, s := range statements {
+					if isWildcardStatement(s) {
+						violations = append(violations, Violation{
+							Resource: r,
+							Message:  fmt.Sprintf("statement %d allows action '*' on resource '*'", i),
+						})
+					}
+				}
+			}
+			return violations
+		},
+	}
+}
+
+// LambdaLogRetention reports Lambda functions without a log group with `retention_in_days`,
+// either referenced by `logging_config.log_group` or named `/aws/lambda/<function name>`.
+func LambdaLogRetention() Rule {
+	return Rule{
+		Name:     "lambda-log-retention",
+		Severity: SeverityError,
+		Check: func(stack *Stack) []Violation {
+			logGroupsByName := map[string]*Resource{}
+			for _, lg := range stack.ResourcesOfType("aws_cloudwatch_log_group") {
+				if name, ok := lg.Config["name"].(string); ok {
+					logGroupsByName[name] = lg
+				}
+			}
+			var violations []Violation
+			for _, fn := range stack.ResourcesOfType("aws_lambda_function") {
+				var logGroup *Resource
+				for _, lc := range toObjects(fn.Config["logging_config"]) {
+					if ref, ok := lc["log_group"].(string); ok {
+						if lg, ok := stack.Reference(ref); ok {
+							logGroup = lg
+						} else if lg, ok := logGroupsByName[ref]; ok {
+							logGroup = lg
+						}
+					}
+				}
+				if name, ok := fn.Config["function_name"].(string); ok && logGroup == nil {
+					logGroup = logGroupsByName["/aws/lambda/"+name]
+				}
+				switch {
+				case logGroup == nil:
+					violations = append(violations, Violation{Resource: fn, Message: "no log group found for function"})
+				case logGroup.Config["retention_in_days"] == nil:
+					violations = append(violations, Violation{
+						Resource: fn,
+						Message:  fmt.Sprintf("log group '%s' has no retention_in_days", logGroup.Address),
+					})
+				}
+			}
+			return violations
+		},
+	}
+}
+
+// NoPublicBuckets reports public S3 bucket ACLs and public access blocks which do not block all public access.
+func NoPublicBuckets() Rule {
+	return Rule{
+		Name:     "no-public-buckets",
+		Severity: SeverityError,
+		Check: func(stack *Stack) []Violation {
+			var violations []Violation
+			for _, acl := range stack.ResourcesOfType("aws_s3_bucket_acl") {
+				if v, ok := acl.Config["acl"].(string); ok && strings.HasPrefix(v, "public-") {
+					violations = append(violations, Violation{Resource: acl, Message: fmt.Sprintf("bucket acl is '%s'", v)})
+				}
+			}
+			for _, pab := range stack.ResourcesOfType("aws_s3_bucket_public_access_block") {
+				var disabled []string
+				for _, setting := range []string{"block_public_acls", "block_public_policy", "ignore_public_acls", "restrict_public_buckets"} {
+					if enabled, ok := pab.Config[setting].(bool); !ok || !enabled {
+						disabled = append(disabled, setting)
+					}
+				}
+				if len(disabled) > 0 {
+					violations = append(violations, Violation{
+						Resource: pab,
+						Message:  fmt.Sprintf("public access not blocked: %s", strings.Join(disabled, ", ")),
+					})
+				}
+			}
+			return violations
+		},
+	}
+}
+
+// taggableTypes are resource types supporting tags which may be synthesized without a `tags` attribute
+var taggableTypes = map[string]bool{
+	"aws_cloudfront_distribution": true,
+	"aws_cloudwatch_log_group":    true,
+	"aws_dynamodb_table":          true,
+	"aws_iam_role":                true,
+	"aws_lambda_function":         true,
+	"aws_s3_bucket":               true,
+	"aws_sfn_state_machine":       true,
+	"aws_sns_topic":               true,
+	"aws_sqs_queue":               true,
+}
+
+func isTaggable(r *Resource) bool {
+	_, hasTags := r.Config["tags"]
+	return hasTags || taggableTypes[r.Type]
+}
+
+// providerDefaultTags returns the tag keys applied by `default_tags` of all aws provider configurations
+func (s *Stack) providerDefaultTags() map[string]bool {
+	keys := map[string]bool{}
+	providers, _ := s.Config["provider"].(map[string]any)
+	// cdktf synthesizes a list of configurations per provider
+	for _, p := range toObjects(providers["aws"]) {
+		for _, dt := range toObjects(p["default_tags"]) {
+			tags, _ := dt["tags"].(map[string]any)
+			for k := range tags {
+				keys[k] = true
+			}
+		}
+	}
+	return keys
+}
+
+// toObjects returns the objects of a block, which may be synthesized as a single object or a list of objects
+func toObjects(v any) []map[string]any {
+	switch b := v.(type) {
+	case map[string]any:
+		return []map[string]any{b}
+	case []any:
+		var result []map[string]any
+		for _, elem := range b {
+			if obj, ok := elem.(map[string]any); ok {
+				result = append(result, obj)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// jsonPolicyStatements returns the statements of a JSON policy document with lower case keys, matching the policy document data source
+func jsonPolicyStatements(policy string) []map[string]any {
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(policy), &doc); err != nil {
+		// unresolved Terraform expression, i.e. `${data.aws_iam_policy_document.X.json}`
+		return nil
+	}
+	var statements []map[string]any
+	for _, s := range toObjects(doc["Statement"]) {
+		statements = append(statements, map[string]any{
+			"effect":    s["Effect"],
+			"actions":   s["Action"],
+			"resources": s["Resource"],
+		})
+	}
+	return statements
+}
+
+func isWildcardStatement(s map[string]any) bool {
+	if effect, ok := s["effect"].(string); ok && effect != "Allow" {
+		return false
+	}
+	return containsWildcard(s["actions"]) && containsWildcard(s["resources"])
+}
+
+// containsWildcard reports whether a string or list of strings contains `*`
+func containsWildcard(v any) bool {
+	switch values := v.(type) {
+	case string:
+		return values == "*"
+	case []any:
+		for _, elem := range values {
+			if s, ok := elem.(string); ok && s == "*" {
+				return true
+			}
+		}
+	}
+	return false
+}
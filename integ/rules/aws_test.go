@@ -0,0 +1,66 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultRules(t *testing.T) {
+	assert.Empty(t, Check(t, compliantFixture, DefaultRules(Options{})...))
+
+	var names []string
+	for _, r := range DefaultRules(Options{AllowPublicBuckets: true}) {
+		names = append(names, r.Name)
+	}
+	assert.Equal(t, []string{"required-tags", "no-wildcard-iam", "lambda-log-retention"}, names)
+}
+
+func TestAwsRules(t *testing.T) {
+	stack := LoadStack(t, violationsFixture)
+	testCases := []struct {
+		rule     Rule
+		expected map[string]string // message by address
+	}{
+		{
+			rule: RequiredTags(SeverityWarning, DefaultRequiredTags...),
+			expected: map[string]string{
+				"aws_cloudwatch_log_group.Function_LogGroup_5F4E3D2C": "missing required tag(s) GridUUID",
+				"aws_lambda_function.Function_7B6A5C4D":               "missing required tag(s) EnvironmentName, GridUUID",
+				"aws_lambda_function.Orphan_3C2B1A0F":                 "missing required tag(s) EnvironmentName, GridUUID",
+			},
+		},
+		{
+			rule: NoWildcardIAM(),
+			expected: map[string]string{
+				"data.aws_iam_policy_document.Admin_Policy_9C8B7A6D": "statement 1 allows action '*' on resource '*'",
+				"aws_iam_role_policy.Role_InlinePolicy_6A5B4C3D":     "statement 0 allows action '*' on resource '*'",
+			},
+		},
+		{
+			rule: LambdaLogRetention(),
+			expected: map[string]string{
+				"aws_lambda_function.Function_7B6A5C4D": "log group 'aws_cloudwatch_log_group.Function_LogGroup_5F4E3D2C' has no retention_in_days",
+				"aws_lambda_function.Orphan_3C2B1A0F":   "no log group found for function",
+			},
+		},
+		{
+			rule: NoPublicBuckets(),
+			expected: map[string]string{
+				"aws_s3_bucket_acl.Bucket_Acl_1F2E3D4C":                               "bucket acl is 'public-read'",
+				"aws_s3_bucket_public_access_block.Bucket_PublicAccessBlock_0A9B8C7D": "public access not blocked: block_public_acls, block_public_policy",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.rule.Name, func(t *testing.T) {
+			actual := map[string]string{}
+			for _, f := range Evaluate(stack, tc.rule) {
+				assert.Equal(t, tc.rule.Name, f.Rule)
+				assert.NotEmpty(t, f.ConstructPath)
+				actual[f.Address] = f.Message
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
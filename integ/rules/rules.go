@@ -0,0 +1,225 @@
+// Package rules runs static checks on the cdk.tf.json of a synthesized app before deploying.
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/envtio/base/integ/redact"
+	"github.com/stretchr/testify/require"
+)
+
+var terratestLogger = redact.Logger
+
+// SynthFileName is the Terraform JSON configuration written by cdktf for each stack
+const SynthFileName = "cdk.tf.json"
+
+// Severity of a Finding, only error-level findings fail the test.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Rule is a static check on a synthesized stack.
+type Rule struct {
+	Name     string                         // Name of the rule in findings
+	Severity Severity                       // Severity of the findings reported by the rule
+	Check    func(stack *Stack) []Violation // Check returns a violation per offending resource
+}
+
+// Violation is a resource which violates a Rule.
+type Violation struct {
+	Resource *Resource
+	Message  string
+}
+
+// Finding is a Violation reported by a Rule.
+type Finding struct {
+	Rule          string   `json:"rule"`
+	Severity      Severity `json:"severity"`
+	Address       string   `json:"address"`       // Terraform resource address, i.e. `aws_s3_bucket.Bucket_1234ABCD`
+	ConstructPath string   `json:"constructPath"` // Construct path, i.e. `stack/Bucket/Resource`
+	Message       string   `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("[%s] %s: %s (%s): %s", f.Severity, f.Rule, f.Address, f.ConstructPath, f.Message)
+}
+
+// Stack is a synthesized cdk.tf.json.
+type Stack struct {
+	Path      string         // Path of the cdk.tf.json
+	Config    map[string]any // Raw Terraform JSON configuration
+	Resources []*Resource    // Managed resources and data sources, sorted by address
+}
+
+// Resource is a managed resource or data source block of a Stack.
+type Resource struct {
+	Mode          string         // `managed` or `data`
+	Type          string         // Resource type, i.e. `aws_s3_bucket`
+	Name          string         // Logical ID
+	Address       string         // Terraform address, i.e. `aws_s3_bucket.Bucket_1234ABCD` or `data.aws_iam_policy_document.Policy_1234ABCD`
+	ConstructPath string         // Construct path from the cdktf metadata
+	Config        map[string]any // Resource configuration
+}
+
+// LoadStack loads a stack from a cdk.tf.json file or a Terraform working dir written by SynthApp. This fails the test on any errors
+func LoadStack(t *testing.T, path string) *Stack {
+	stack, err := LoadStackE(path)
+	require.NoError(t, err)
+	return stack
+}
+
+// LoadStackE loads a stack from a cdk.tf.json file or a Terraform working dir written by SynthApp.
+func LoadStackE(path string) (*Stack, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, SynthFileName)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	stack, err := ParseStack(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+	stack.Path = path
+	return stack, nil
+}
+
+// ParseStack parses a Terraform JSON configuration.
+func ParseStack(data []byte) (*Stack, error) {
+	var config map[string]any
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	stack := &Stack{Config: config}
+	for mode, key := range map[string]string{"managed": "resource", "data": "data"} {
+		blocks, _ := config[key].(map[string]any)
+		for resourceType, byName := range blocks {
+			resources, _ := byName.(map[string]any)
+			for name, c := range resources {
+				resourceConfig, _ := c.(map[string]any)
+				stack.Resources = append(stack.Resources, newResource(mode, resourceType, name, resourceConfig))
+			}
+		}
+	}
+	sort.Slice(stack.Resources, func(i, j int) bool { return stack.Resources[i].Address < stack.Resources[j].Address })
+	return stack, nil
+}
+
+func newResource(mode, resourceType, name string, config map[string]any) *Resource {
+	address := resourceType + "." + name
+	if mode == "data" {
+		address = "data." + address
+	}
+	r := &Resource{
+		Mode:    mode,
+		Type:    resourceType,
+		Name:    name,
+		Address: address,
+		Config:  config,
+	}
+	// "//": {"metadata": {"path": "stack/Bucket/Resource", "uniqueId": "Bucket_1234ABCD"}}
+	if meta, ok := config["//"].(map[string]any); ok {
+		if metadata, ok := meta["metadata"].(map[string]any); ok {
+			r.ConstructPath, _ = metadata["path"].(string)
+		}
+	}
+	return r
+}
+
+// ResourcesOfType returns the managed resources of the given type.
+func (s *Stack) ResourcesOfType(resourceType string) []*Resource {
+	var result []*Resource
+	for _, r := range s.Resources {
+		if r.Mode == "managed" && r.Type == resourceType {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// Resource returns the resource or data source at the address.
+func (s *Stack) Resource(address string) (*Resource, bool) {
+	for _, r := range s.Resources {
+		if r.Address == address {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// Reference returns the resource referenced by a Terraform expression, i.e. `${aws_cloudwatch_log_group.LogGroup_1234ABCD.name}`.
+func (s *Stack) Reference(expression string) (*Resource, bool) {
+	ref, ok := strings.CutPrefix(expression, "${")
+	if !ok {
+		return nil, false
+	}
+	ref = strings.TrimSuffix(ref, "}")
+	parts := strings.Split(ref, ".")
+	if parts[0] == "data" && len(parts) >= 3 {
+		return s.Resource(strings.Join(parts[:3], "."))
+	}
+	if len(parts) >= 2 {
+		return s.Resource(strings.Join(parts[:2], "."))
+	}
+	return nil, false
+}
+
+// Evaluate runs the rules on the stack and returns all findings.
+func Evaluate(stack *Stack, rules ...Rule) []Finding {
+	var findings []Finding
+	for _, rule := range rules {
+		severity := rule.Severity
+		if severity == "" {
+			severity = SeverityError
+		}
+		for _, v := range rule.Check(stack) {
+			f := Finding{Rule: rule.Name, Severity: severity, Message: v.Message}
+			if v.Resource != nil {
+				f.Address = v.Resource.Address
+				f.ConstructPath = v.Resource.ConstructPath
+			}
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// Check runs the rules on the stack synthesized to tfWorkingDir and logs all findings.
+// Fails the test on error-level findings.
+func Check(t *testing.T, tfWorkingDir string, rules ...Rule) []Finding {
+	findings, err := CheckE(t, tfWorkingDir, rules...)
+	require.NoError(t, err)
+	return findings
+}
+
+// CheckE runs the rules on the stack synthesized to tfWorkingDir and logs all findings.
+// Returns an error listing the error-level findings.
+func CheckE(t *testing.T, tfWorkingDir string, rules ...Rule) ([]Finding, error) {
+	stack, err := LoadStackE(tfWorkingDir)
+	if err != nil {
+		return nil, err
+	}
+	findings := Evaluate(stack, rules...)
+	var errs []string
+	for _, f := range findings {
+		terratestLogger.Logf(t, "%s", f)
+		if f.Severity == SeverityError {
+			errs = append(errs, f.String())
+		}
+	}
+	terratestLogger.Logf(t, "Checked %d rule(s) on %d resource(s) of %s: %d finding(s)", len(rules), len(stack.Resources), stack.Path, len(findings))
+	if len(errs) > 0 {
+		return findings, fmt.Errorf("%d error-level rule finding(s) in %s:\n  %s", len(errs), stack.Path, strings.Join(errs, "\n  "))
+	}
+	return findings, nil
+}
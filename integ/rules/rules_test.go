@@ -0,0 +1,102 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	compliantFixture  = "../fixtures/synth/compliant"
+	violationsFixture = "../fixtures/synth/violations"
+)
+
+func TestLoadStack(t *testing.T) {
+	stack := LoadStack(t, compliantFixture)
+	assert.Equal(t, "../fixtures/synth/compliant/cdk.tf.json", stack.Path)
+
+	var addresses []string
+	for _, r := range stack.Resources {
+		addresses = append(addresses, r.Address)
+	}
+	assert.Equal(t, []string{
+		"aws_cloudwatch_log_group.Function_LogGroup_8E7D1A2B",
+		"aws_lambda_function.Function_1A2B3C4D",
+		"aws_s3_bucket.Bucket_83908E77",
+		"aws_s3_bucket_public_access_block.Bucket_PublicAccessBlock_2D5A3E1F",
+		"data.aws_iam_policy_document.Function_ServiceRole_Policy_4B1C2F3A",
+	}, addresses)
+
+	r, ok := stack.Resource("data.aws_iam_policy_document.Function_ServiceRole_Policy_4B1C2F3A")
+	require.True(t, ok)
+	assert.Equal(t, "data", r.Mode)
+	assert.Equal(t, "aws_iam_policy_document", r.Type)
+	assert.Equal(t, "compliant/Function/ServiceRole/Policy", r.ConstructPath)
+
+	assert.Len(t, stack.ResourcesOfType("aws_lambda_function"), 1)
+	assert.Empty(t, stack.ResourcesOfType("aws_iam_policy_document"), "data sources are not managed resources")
+
+	_, err := LoadStackE("../fixtures/synth/missing")
+	assert.Error(t, err)
+	_, err = ParseStack([]byte("{"))
+	assert.Error(t, err)
+}
+
+func TestStack_Reference(t *testing.T) {
+	stack := LoadStack(t, compliantFixture)
+	testCases := []struct {
+		expression string
+		expected   string
+	}{
+		{"${aws_cloudwatch_log_group.Function_LogGroup_8E7D1A2B.name}", "aws_cloudwatch_log_group.Function_LogGroup_8E7D1A2B"},
+		{"${data.aws_iam_policy_document.Function_ServiceRole_Policy_4B1C2F3A.json}", "data.aws_iam_policy_document.Function_ServiceRole_Policy_4B1C2F3A"},
+		{"${aws_s3_bucket.Missing.bucket}", ""},
+		{"/aws/lambda/compliant-function", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			r, ok := stack.Reference(tc.expression)
+			if tc.expected == "" {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tc.expected, r.Address)
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	stack := LoadStack(t, compliantFixture)
+	everyBucket := Rule{
+		Name: "every-bucket",
+		Check: func(stack *Stack) []Violation {
+			var violations []Violation
+			for _, r := range stack.ResourcesOfType("aws_s3_bucket") {
+				violations = append(violations, Violation{Resource: r, Message: "bucket found"})
+			}
+			return violations
+		},
+	}
+	findings := Evaluate(stack, everyBucket)
+	require.Len(t, findings, 1)
+	assert.Equal(t, Finding{
+		Rule:          "every-bucket",
+		Severity:      SeverityError,
+		Address:       "aws_s3_bucket.Bucket_83908E77",
+		ConstructPath: "compliant/Bucket/Resource",
+		Message:       "bucket found",
+	}, findings[0], "severity defaults to error")
+
+	everyBucket.Severity = SeverityWarning
+	findings, err := CheckE(t, compliantFixture, everyBucket)
+	assert.NoError(t, err, "warnings must not fail the check")
+	assert.Len(t, findings, 1)
+
+	everyBucket.Severity = SeverityError
+	_, err = CheckE(t, compliantFixture, everyBucket)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 error-level rule finding(s)")
+	assert.Contains(t, err.Error(), "[error] every-bucket: aws_s3_bucket.Bucket_83908E77 (compliant/Bucket/Resource): bucket found")
+}
@@ -0,0 +1,240 @@
+package integ
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/environment-toolkit/go-synth/executors"
+	util "github.com/envtio/base/integ/aws"
+	"github.com/envtio/base/integ/plan"
+	"github.com/envtio/base/integ/rules"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/require"
+)
+
+// Stages run by Run, set `SKIP_<stage>=true` to skip a stage, i.e. `SKIP_cleanup_terraform=true` to keep the app deployed
+const (
+	StageSynthApp         = "synth_app"
+	StageDeployTerraform  = "deploy_terraform"
+	StageCheckIdempotent  = "check_idempotent"
+	StageValidate         = "validate"
+	StageCheckDrift       = "check_drift"
+	StageImportRoundTrip  = "import_roundtrip"
+	StageCleanupTerraform = "cleanup_terraform"
+)
+
+const (
+	// DefaultRegion is the AWS region of a Scenario without Region
+	DefaultRegion = "us-east-1"
+	// DefaultEnvironmentName is the ENVIRONMENT_NAME of a Scenario, unless set in Env
+	DefaultEnvironmentName = "test"
+)
+
+// Validator validates a deployed test app.
+type Validator func(t *testing.T, tfWorkingDir string, awsRegion string)
+
+// Scenario is an integration test of a test app, see Run.
+type Scenario struct {
+	App             string              // Name of the test app in `apps/<App>.ts`, required
+	Region          string              // AWS region of the app (AWS_REGION), defaults to DefaultRegion
+	Env             map[string]string   // Env vars of the app, merged over the os environment and the AWS_REGION, ENVIRONMENT_NAME and STACK_NAME defaults
+	AppDirs         []string            // Directories of `apps/` copied to the synth app fs, i.e. `handlers`
+	RetryableErrors map[string]string   // Additional retryable Terraform errors of the deploy
+	Rules           rules.Options       // Options of the rules checked after synth
+	Validate        Validator           // Validates the deployed app, optional
+	Idempotency     []plan.Expectation  // Expectations of the plan right after deploy, defaults to plan.ExpectEmptyPlan()
+	ImportRoundTrip *plan.ImportOptions // Resources re-imported by the import_roundtrip stage after check_drift, no round-trip if nil
+	Upgrade         *Upgrade            // Deploys a published library version and upgrades it to the local `lib/`, defaults to $UPGRADE_FROM_VERSION
+	Day2            []Day2Step          // Changes applied to the deployed app in order, after Validate and the Upgrade
+	FailOnWarnings  bool                // Fail synth_app on warning annotations, i.e. construct deprecations. Error annotations always fail
+	AllStacks       bool                // Synth all stacks of the app (see util.SynthStacks) and deploy them in dependency order
+	Serial          bool                // Run the scenario without t.Parallel, i.e. when apps share an account-wide resource
+
+	StackName    string         // Stack name of the app (STACK_NAME), defaults to App
+	TfWorkingDir string         // Terraform working dir, defaults to `tf/<App>`
+	Variables    util.Variables // Go template variables of the app, see util.RunMatrix
+}
+
+// Run runs the scenario as a parallel test (unless Serial) in the stages synth_app, deploy_terraform, check_idempotent, validate,
+// check_drift, import_roundtrip, the Day-2 steps (see Day2Step) and cleanup_terraform. check_idempotent requires an
+// empty plan right after deploy, check_drift requires a refresh-only plan without changes made outside of Terraform
+// after validate. import_roundtrip runs if ImportRoundTrip is set and requires the resources removed from state to
+// import without a diff, see plan.ImportRoundTrip. Cleanup runs even if a stage fails, unless SKIP_cleanup_terraform is set.
+// The working dir is also destroyed on SIGINT/SIGTERM and by a watchdog before the test deadline (see RegisterCleanup),
+// deploy_terraform writes a cleanup marker removed by the destroy, see CleanupPending.
+//
+// With AllStacks, deploy_terraform deploys the stacks in dependency order, check_idempotent and check_drift check every
+// stack and cleanup destroys them in reverse order. Validate receives the working dir of the manifest, look up the
+// stacks with util.LoadStacks. ImportRoundTrip, Upgrade and Day2 are not supported with AllStacks.
+//
+// In upgrade mode (see Upgrade) synth_app synths the app with the published library, so deploy_terraform deploys
+// the published version. The upgrade stages upgrade_app, plan_upgrade, apply_upgrade and validate_upgrade run before
+// the Day-2 steps, re-synth the app against the local `lib/`, check the plan against the upgrade expectations, write
+// the upgrade report (see UpgradeReport) and validate the upgraded app.
+//
+//	func TestFifoQueue(t *testing.T) {
+//		integ.Run(t, integ.Scenario{
+//			App:      "fifo-queue",
+//			Validate: validateFifoQueue,
+//		})
+//	}
+func Run(t *testing.T, s Scenario) {
+	if !s.Serial {
+		t.Parallel()
+	}
+	if s.App == "" {
+		t.Fatal("Scenario App is required")
+	}
+	if s.AllStacks && (s.ImportRoundTrip != nil || s.Upgrade != nil || len(s.Day2) > 0) {
+		t.Fatal("Scenario ImportRoundTrip, Upgrade and Day2 are not supported with AllStacks")
+	}
+	awsRegion := s.region()
+	tfWorkingDir := s.workingDir()
+	env := s.env()
+	upgrade := s.upgrade(t)
+	var dependencies map[string]string
+	if upgrade != nil {
+		if upgrade.From == "" {
+			t.Fatal("Scenario Upgrade From is required")
+		}
+		dependencies = upgrade.dependencies()
+	}
+
+	keepDeployed := os.Getenv("SKIP_"+StageCleanupTerraform) != ""
+	if !keepDeployed {
+		RegisterCleanup(t, tfWorkingDir)
+	}
+	defer test_structure.RunTestStage(t, StageCleanupTerraform, func() {
+		cleanup(t, tfWorkingDir)
+	})
+
+	test_structure.RunTestStage(t, StageSynthApp, func() {
+		s.synth(t, tfWorkingDir, env, s.Variables, dependencies)
+	})
+	test_structure.RunTestStage(t, StageDeployTerraform, func() {
+		if !keepDeployed {
+			require.NoError(t, WriteCleanupMarkerE(tfWorkingDir, t.Name()))
+		}
+		if s.AllStacks {
+			util.DeployStacksUsingTerraform(t, tfWorkingDir, s.RetryableErrors)
+		} else {
+			util.DeployUsingTerraform(t, tfWorkingDir, s.RetryableErrors)
+		}
+	})
+	test_structure.RunTestStage(t, StageCheckIdempotent, func() {
+		for _, dir := range s.stackDirs(t, tfWorkingDir) {
+			plan.Replan(t, dir, s.idempotency()...)
+		}
+	})
+	if s.Validate != nil {
+		test_structure.RunTestStage(t, StageValidate, func() {
+			s.Validate(t, tfWorkingDir, awsRegion)
+		})
+	}
+	test_structure.RunTestStage(t, StageCheckDrift, func() {
+		for _, dir := range s.stackDirs(t, tfWorkingDir) {
+			plan.CheckDrift(t, dir)
+		}
+	})
+	if s.ImportRoundTrip != nil {
+		test_structure.RunTestStage(t, StageImportRoundTrip, func() {
+			plan.ImportRoundTrip(t, tfWorkingDir, *s.ImportRoundTrip)
+		})
+	}
+
+	if upgrade != nil {
+		s.runUpgrade(t, *upgrade, tfWorkingDir, awsRegion, env)
+	}
+
+	if len(s.Day2) > 0 && os.Getenv(SkipDay2EnvVar) != "" {
+		terratestLogger.Logf(t, "The '%s' environment variable is set, so skipping %d Day-2 step(s)", SkipDay2EnvVar, len(s.Day2))
+		return
+	}
+	vars := maps.Clone(s.Variables)
+	for _, step := range s.Day2 {
+		maps.Copy(env, step.Env)
+		if step.Variables != nil {
+			if vars == nil {
+				vars = util.Variables{}
+			}
+			maps.Copy(vars, step.Variables)
+		}
+		s.runDay2Step(t, step, tfWorkingDir, awsRegion, env, vars)
+	}
+}
+
+func (s Scenario) region() string {
+	if s.Region == "" {
+		return DefaultRegion
+	}
+	return s.Region
+}
+
+func (s Scenario) workingDir() string {
+	if s.TfWorkingDir == "" {
+		return filepath.Join("tf", s.App)
+	}
+	return s.TfWorkingDir
+}
+
+func (s Scenario) idempotency() []plan.Expectation {
+	if len(s.Idempotency) == 0 {
+		return []plan.Expectation{plan.ExpectEmptyPlan()}
+	}
+	return s.Idempotency
+}
+
+func (s Scenario) stackName() string {
+	if s.StackName == "" {
+		return s.App
+	}
+	return s.StackName
+}
+
+// env returns the env vars of the app: the os environment, the defaults and Env
+func (s Scenario) env() map[string]string {
+	env := executors.EnvMap(os.Environ())
+	env["AWS_REGION"] = s.region()
+	env["ENVIRONMENT_NAME"] = DefaultEnvironmentName
+	env["STACK_NAME"] = s.stackName()
+	maps.Copy(env, s.Env)
+	return env
+}
+
+// stackDirs returns the Terraform working dirs of the stacks in deployment order, tfWorkingDir unless AllStacks
+func (s Scenario) stackDirs(t *testing.T, tfWorkingDir string) []string {
+	if !s.AllStacks {
+		return []string{tfWorkingDir}
+	}
+	stacks := util.LoadStacks(t, tfWorkingDir)
+	dirs := make([]string, 0, len(stacks.Order))
+	for _, name := range stacks.Order {
+		dirs = append(dirs, stacks.WorkingDir(name))
+	}
+	return dirs
+}
+
+// synth synths the app with the dependency overrides and checks the rules
+func (s Scenario) synth(t *testing.T, tfWorkingDir string, env map[string]string, vars util.Variables, dependencies map[string]string) {
+	util.SynthAppWithOptions(t, util.SynthOptions{
+		TestApp:           s.App,
+		TfWorkingDir:      tfWorkingDir,
+		Env:               env,
+		AdditionalAppDirs: s.AppDirs,
+		StackName:         env["STACK_NAME"],
+		AllStacks:         s.AllStacks,
+		Variables:         vars,
+		Dependencies:      dependencies,
+		FailOnWarnings:    s.FailOnWarnings,
+	})
+	for _, dir := range s.stackDirs(t, tfWorkingDir) {
+		rules.Check(t, dir, rules.DefaultRules(s.Rules)...)
+	}
+}
+
+// cleanup destroys the app or its stacks if deployed, unless the watchdog or signal handler is destroying it
+func cleanup(t *testing.T, tfWorkingDir string) {
+	require.NoError(t, cleanups.destroyE(t, tfWorkingDir))
+}
@@ -0,0 +1,143 @@
+package integ
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	util "github.com/envtio/base/integ/aws"
+	"github.com/envtio/base/integ/plan"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScenario_Env(t *testing.T) {
+	t.Setenv("AWS_REGION", "eu-west-1")
+	t.Setenv("ENVIRONMENT_NAME", "from-shell")
+	t.Setenv("HOME_REGION_TEST", "kept")
+
+	env := Scenario{App: "fifo-queue"}.env()
+	assert.Equal(t, DefaultRegion, env["AWS_REGION"])
+	assert.Equal(t, DefaultEnvironmentName, env["ENVIRONMENT_NAME"])
+	assert.Equal(t, "fifo-queue", env["STACK_NAME"])
+	assert.Equal(t, "kept", env["HOME_REGION_TEST"])
+
+	env = Scenario{
+		App:       "fifo-queue",
+		Region:    "ap-southeast-1",
+		StackName: "fifo-queue-arm64",
+		Env:       map[string]string{"ENVIRONMENT_NAME": "renamed", "MAX_RECEIVE_COUNT": "2"},
+	}.env()
+	assert.Equal(t, "ap-southeast-1", env["AWS_REGION"])
+	assert.Equal(t, "renamed", env["ENVIRONMENT_NAME"])
+	assert.Equal(t, "fifo-queue-arm64", env["STACK_NAME"])
+	assert.Equal(t, "2", env["MAX_RECEIVE_COUNT"])
+}
+
+func TestScenario_WorkingDir(t *testing.T) {
+	assert.Equal(t, filepath.Join("tf", "fifo-queue"), Scenario{App: "fifo-queue"}.workingDir())
+	assert.Equal(t, "tf/app/app-arm64", Scenario{App: "app", TfWorkingDir: "tf/app/app-arm64"}.workingDir())
+}
+
+func TestRun_SkipStages(t *testing.T) {
+	for _, stage := range []string{StageSynthApp, StageDeployTerraform, StageCheckIdempotent, StageCheckDrift, StageImportRoundTrip, StageCleanupTerraform, "upgrade_app", "plan_upgrade", "apply_upgrade", "rename_app"} {
+		t.Setenv("SKIP_"+stage, "true")
+	}
+	var validated []string
+	t.Run("group", func(t *testing.T) {
+		t.Run("scenario", func(t *testing.T) {
+			Run(t, Scenario{
+				App:    "fifo-queue",
+				Region: "eu-west-1",
+				Validate: func(t *testing.T, tfWorkingDir, awsRegion string) {
+					validated = append(validated, StageValidate+" "+tfWorkingDir+" "+awsRegion)
+				},
+				Upgrade: &Upgrade{From: "0.1.0"},
+				Day2: []Day2Step{{
+					Name: "rename",
+					Env:  map[string]string{"ENVIRONMENT_NAME": "renamed"},
+					Validate: func(t *testing.T, tfWorkingDir, awsRegion string) {
+						validated = append(validated, "validate_rename "+tfWorkingDir)
+					},
+				}},
+			})
+		})
+	})
+	assert.Equal(t, []string{
+		"validate " + filepath.Join("tf", "fifo-queue") + " eu-west-1",
+		"validate " + filepath.Join("tf", "fifo-queue") + " eu-west-1",
+		"validate_rename " + filepath.Join("tf", "fifo-queue"),
+	}, validated)
+}
+
+func TestRun_Serial(t *testing.T) {
+	for _, stage := range []string{StageSynthApp, StageDeployTerraform, StageCheckIdempotent, StageCheckDrift, StageCleanupTerraform} {
+		t.Setenv("SKIP_"+stage, "true")
+	}
+	// t.Setenv panics in parallel tests
+	Run(t, Scenario{
+		App:    "fifo-queue",
+		Serial: true,
+		Validate: func(t *testing.T, tfWorkingDir, awsRegion string) {
+			t.Setenv("VALIDATED", "true")
+		},
+	})
+}
+
+func TestRun_SkipDay2(t *testing.T) {
+	for _, stage := range []string{StageSynthApp, StageDeployTerraform, StageCheckIdempotent, StageCheckDrift, StageCleanupTerraform} {
+		t.Setenv("SKIP_"+stage, "true")
+	}
+	t.Setenv(SkipDay2EnvVar, "true")
+	var validated []string
+	Run(t, Scenario{
+		App:    "dlq-queue",
+		Serial: true,
+		Validate: func(t *testing.T, tfWorkingDir, awsRegion string) {
+			validated = append(validated, StageValidate)
+		},
+		Day2: []Day2Step{{
+			Name:   "raise_max_receive_count",
+			Expect: []plan.Expectation{plan.ExpectNoReplace()},
+			Validate: func(t *testing.T, tfWorkingDir, awsRegion string) {
+				validated = append(validated, "validate_raise_max_receive_count")
+			},
+		}},
+	})
+	assert.Equal(t, []string{StageValidate}, validated)
+}
+
+func TestScenario_StackDirs(t *testing.T) {
+	assert.Equal(t, []string{"tf/app"}, Scenario{App: "app"}.stackDirs(t, "tf/app"))
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, util.ManifestFileName), []byte(`{
+		"version": "0.20.9",
+		"stacks": {
+			"app": {"name": "app", "workingDirectory": "stacks/app", "dependencies": ["network"]},
+			"network": {"name": "network", "workingDirectory": "stacks/network"}
+		}
+	}`), 0644))
+	assert.Equal(t, []string{
+		filepath.Join(dir, "stacks", "network"),
+		filepath.Join(dir, "stacks", "app"),
+	}, Scenario{App: "app", AllStacks: true}.stackDirs(t, dir))
+}
+
+func TestScenario_Idempotency(t *testing.T) {
+	updated := &terraform.PlanStruct{ResourceChangesMap: map[string]*tfjson.ResourceChange{
+		"aws_iam_policy.policy": {
+			Address: "aws_iam_policy.policy",
+			Type:    "aws_iam_policy",
+			Change:  &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionUpdate}},
+		},
+	}}
+	// perpetual diffs fail by default
+	assert.Error(t, plan.CheckE(updated, Scenario{}.idempotency()...))
+	// unless tolerated by the scenario
+	assert.NoError(t, plan.CheckE(updated, Scenario{
+		Idempotency: []plan.Expectation{plan.ExpectOnlyUpdates("aws_iam_policy")},
+	}.idempotency()...))
+}
@@ -0,0 +1,413 @@
+// Package state reads Terraform outputs and resources directly from a local terraform.tfstate (v4 format),
+// without the terraform binary or an initialized working dir.
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/envtio/base/integ"
+	"github.com/envtio/base/integ/redact"
+	"github.com/stretchr/testify/require"
+)
+
+// StateFileName is the name of the local state file in a Terraform working dir
+const StateFileName = "terraform.tfstate"
+
+// supportedVersion is the state format version written by Terraform >= 0.12 and OpenTofu
+const supportedVersion = 4
+
+// ref https://github.com/hashicorp/terraform/blob/v1.8.2/internal/states/statefile/version4.go
+
+// State is a parsed terraform.tfstate.
+type State struct {
+	Version          int               `json:"version"`
+	TerraformVersion string            `json:"terraform_version"`
+	Serial           uint64            `json:"serial"`
+	Lineage          string            `json:"lineage"`
+	Outputs          map[string]Output `json:"outputs"`
+	Resources        []Resource        `json:"resources"`
+}
+
+// Output is a root module output value.
+type Output struct {
+	Value     any             `json:"value"`
+	Type      json.RawMessage `json:"type"`
+	Sensitive bool            `json:"sensitive,omitempty"`
+}
+
+// Resource is a resource block, with an instance per `count` or `for_each` key.
+type Resource struct {
+	Module    string     `json:"module,omitempty"` // Module address, empty for the root module
+	Mode      string     `json:"mode"`             // `managed` or `data`
+	Type      string     `json:"type"`
+	Name      string     `json:"name"`
+	Each      string     `json:"each,omitempty"` // `list` for count, `map` for for_each
+	Provider  string     `json:"provider"`
+	Instances []Instance `json:"instances"`
+}
+
+// Instance is a single instance of a Resource.
+type Instance struct {
+	IndexKey            any             `json:"index_key,omitempty"` // number for count, string for for_each
+	SchemaVersion       uint64          `json:"schema_version"`
+	Attributes          map[string]any  `json:"attributes"`
+	SensitiveAttributes json.RawMessage `json:"sensitive_attributes,omitempty"`
+	Dependencies        []string        `json:"dependencies,omitempty"`
+
+	sensitivePaths [][]any // Parsed SensitiveAttributes, steps are attribute names or map keys (string) and list indexes (int)
+}
+
+// ResourceInstance is a resource instance with its full address, i.e. `module.app.aws_s3_object.this["key"]`.
+type ResourceInstance struct {
+	Address    string
+	Resource   *Resource
+	Attributes map[string]any // Attributes with the values at the sensitive attribute paths masked, see State.RevealAttributes
+
+	instance *Instance
+}
+
+// pathStep is a step of a sensitive attribute path, ref https://github.com/hashicorp/terraform/blob/v1.8.2/internal/states/statefile/version4.go
+type pathStep struct {
+	Type  string          `json:"type"` // `get_attr` or `index`
+	Value json.RawMessage `json:"value"`
+}
+
+// parseSensitivePaths parses the sensitive attribute paths of the instance
+func (inst *Instance) parseSensitivePaths() error {
+	if len(inst.SensitiveAttributes) == 0 {
+		return nil
+	}
+	var paths [][]pathStep
+	if err := json.Unmarshal(inst.SensitiveAttributes, &paths); err != nil {
+		return fmt.Errorf("error parsing sensitive attributes: %v", err)
+	}
+	inst.sensitivePaths = make([][]any, 0, len(paths))
+	for _, path := range paths {
+		steps := make([]any, 0, len(path))
+		for _, step := range path {
+			switch step.Type {
+			case "get_attr":
+				var name string
+				if err := json.Unmarshal(step.Value, &name); err != nil {
+					return fmt.Errorf("error parsing sensitive attribute step: %v", err)
+				}
+				steps = append(steps, name)
+			case "index":
+				var key struct {
+					Value any    `json:"value"`
+					Type  string `json:"type"`
+				}
+				if err := json.Unmarshal(step.Value, &key); err != nil {
+					return fmt.Errorf("error parsing sensitive attribute step: %v", err)
+				}
+				switch k := key.Value.(type) {
+				case string:
+					steps = append(steps, k)
+				case float64:
+					steps = append(steps, int(k))
+				default:
+					return fmt.Errorf("unsupported sensitive attribute index '%s'", string(step.Value))
+				}
+			default:
+				return fmt.Errorf("unsupported sensitive attribute step type '%s'", step.Type)
+			}
+		}
+		inst.sensitivePaths = append(inst.sensitivePaths, steps)
+	}
+	return nil
+}
+
+// maskedAttributes returns a copy of the attributes with the values at the sensitive paths masked
+func (inst *Instance) maskedAttributes() map[string]any {
+	var masked any = inst.Attributes
+	for _, path := range inst.sensitivePaths {
+		masked = maskPath(masked, path)
+	}
+	attributes, _ := masked.(map[string]any)
+	return attributes
+}
+
+// maskPath returns a copy of value with the value at path masked, value is returned as is if the path does not exist
+func maskPath(value any, path []any) any {
+	if len(path) == 0 {
+		return redact.MaskValue(value)
+	}
+	switch step := path[0].(type) {
+	case string:
+		m, ok := value.(map[string]any)
+		if !ok {
+			return value
+		}
+		elem, ok := m[step]
+		if !ok {
+			return value
+		}
+		result := make(map[string]any, len(m))
+		for k, v := range m {
+			result[k] = v
+		}
+		result[step] = maskPath(elem, path[1:])
+		return result
+	case int:
+		l, ok := value.([]any)
+		if !ok || step < 0 || step >= len(l) {
+			return value
+		}
+		result := append([]any{}, l...)
+		result[step] = maskPath(l[step], path[1:])
+		return result
+	default:
+		return value
+	}
+}
+
+// Load loads the state from a state file or a Terraform working dir. This fails the test on any errors
+func Load(t *testing.T, path string) *State {
+	s, err := LoadE(path)
+	require.NoError(t, err)
+	return s
+}
+
+// LoadE loads the state from a state file or a Terraform working dir.
+//
+// A working dir must contain terraform.tfstate or a single `*.tfstate` file, i.e. the `<stack name>.tfstate` LocalBackend path of the test apps.
+func LoadE(path string) (*State, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		if path, err = findStateFile(path); err != nil {
+			return nil, err
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing state file %s: %v", path, err)
+	}
+	return s, nil
+}
+
+// findStateFile returns the state file in the working dir
+func findStateFile(dir string) (string, error) {
+	path := filepath.Join(dir, StateFileName)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	stateFiles, err := filepath.Glob(filepath.Join(dir, "*.tfstate"))
+	if err != nil {
+		return "", err
+	}
+	switch len(stateFiles) {
+	case 0:
+		return "", fmt.Errorf("no state file found in %s", dir)
+	case 1:
+		return stateFiles[0], nil
+	default:
+		return "", fmt.Errorf("multiple state files found in %s: %s", dir, strings.Join(stateFiles, ", "))
+	}
+}
+
+// Parse parses the contents of a v4 state file.
+func Parse(data []byte) (*State, error) {
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if s.Version != supportedVersion {
+		return nil, fmt.Errorf("unsupported state version %d, expected %d", s.Version, supportedVersion)
+	}
+	for i := range s.Resources {
+		r := &s.Resources[i]
+		for j := range r.Instances {
+			if err := r.Instances[j].parseSensitivePaths(); err != nil {
+				return nil, fmt.Errorf("%s: %v", r.instanceAddress(r.Instances[j].IndexKey), err)
+			}
+		}
+	}
+	return &s, nil
+}
+
+// OutputValues returns the output values by name, like `terraform output -json` without the metadata.
+func (s *State) OutputValues() map[string]any {
+	values := make(map[string]any, len(s.Outputs))
+	for name, o := range s.Outputs {
+		values[name] = o.Value
+	}
+	return values
+}
+
+// OutputSnapshot returns an integ.Outputs snapshot of the output values, which can be searched and bound to structs.
+// Sensitive outputs are masked (see integ.Outputs.RevealSensitive) and registered for redaction in logs until the test completes.
+func (s *State) OutputSnapshot(t *testing.T) *integ.Outputs {
+	var sensitive []string
+	for name, o := range s.Outputs {
+		if o.Sensitive {
+			redact.RegisterValue(t, o.Value)
+			sensitive = append(sensitive, name)
+		}
+	}
+	return integ.NewOutputs(s.OutputValues(), sensitive...)
+}
+
+// maskedOutputValues returns the output values by name, the values of sensitive outputs are masked.
+func (s *State) maskedOutputValues() map[string]any {
+	values := make(map[string]any, len(s.Outputs))
+	for name, o := range s.Outputs {
+		if o.Sensitive {
+			values[name] = redact.MaskValue(o.Value)
+		} else {
+			values[name] = o.Value
+		}
+	}
+	return values
+}
+
+// ResourceInstances returns all resource instances sorted by address, the values of sensitive attributes are masked.
+func (s *State) ResourceInstances() []ResourceInstance {
+	var result []ResourceInstance
+	for i := range s.Resources {
+		r := &s.Resources[i]
+		for j := range r.Instances {
+			inst := &r.Instances[j]
+			result = append(result, ResourceInstance{
+				Address:    r.instanceAddress(inst.IndexKey),
+				Resource:   r,
+				Attributes: inst.maskedAttributes(),
+				instance:   inst,
+			})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Address < result[j].Address })
+	return result
+}
+
+// Resource returns the resource instance at the address, i.e. `aws_s3_bucket.this` or `module.app.data.aws_iam_policy_document.this[0]`.
+func (s *State) Resource(address string) (ResourceInstance, bool) {
+	for _, ri := range s.ResourceInstances() {
+		if ri.Address == address {
+			return ri, true
+		}
+	}
+	return ResourceInstance{}, false
+}
+
+// Attributes returns the attributes of the resource instance at the address, the values of sensitive attributes are masked.
+func (s *State) Attributes(address string) (map[string]any, error) {
+	ri, ok := s.Resource(address)
+	if !ok {
+		return nil, fmt.Errorf("resource '%s' not found in state", address)
+	}
+	return ri.Attributes, nil
+}
+
+// RevealAttributes returns the attributes of the resource instance at the address including the values of sensitive
+// attributes, which are registered for redaction in logs until the test completes.
+func (s *State) RevealAttributes(t *testing.T, address string) (map[string]any, error) {
+	ri, ok := s.Resource(address)
+	if !ok {
+		return nil, fmt.Errorf("resource '%s' not found in state", address)
+	}
+	for _, path := range ri.instance.sensitivePaths {
+		if value, ok := valueAt(ri.instance.Attributes, path); ok {
+			redact.RegisterValue(t, value)
+		}
+	}
+	return ri.instance.Attributes, nil
+}
+
+// valueAt returns the value at the sensitive attribute path
+func valueAt(value any, path []any) (any, bool) {
+	for _, step := range path {
+		switch s := step.(type) {
+		case string:
+			m, ok := value.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if value, ok = m[s]; !ok {
+				return nil, false
+			}
+		case int:
+			l, ok := value.([]any)
+			if !ok || s < 0 || s >= len(l) {
+				return nil, false
+			}
+			value = l[s]
+		}
+	}
+	return value, true
+}
+
+// SearchAttributes searches the attributes of the resource instance at the address using JMESPath,
+// the values of sensitive attributes are masked.
+func (s *State) SearchAttributes(address, query string) (any, error) {
+	attributes, err := s.Attributes(address)
+	if err != nil {
+		return nil, err
+	}
+	p, err := integ.CompileJMESPath(query)
+	if err != nil {
+		return nil, err
+	}
+	return p.Search(attributes)
+}
+
+// Search searches the state using JMESPath, custom functions registered with integ.RegisterJMESPathFunction are available.
+//
+// The searched document has the output values under `outputs` and the resource attributes by address under `resources`,
+// sensitive outputs and attributes are masked like in OutputSnapshot and Attributes:
+//
+//	outputs.bucket.arn
+//	resources."aws_s3_bucket.this".arn
+//	resources.* | [?tags.Environment == 'test'].id
+func (s *State) Search(query string) (any, error) {
+	p, err := integ.CompileJMESPath(query)
+	if err != nil {
+		return nil, err
+	}
+	resources := map[string]any{}
+	for _, ri := range s.ResourceInstances() {
+		resources[ri.Address] = ri.Attributes
+	}
+	return p.Search(map[string]any{
+		"outputs":   s.maskedOutputValues(),
+		"resources": resources,
+	})
+}
+
+// address returns the resource address without instance key
+func (r *Resource) address() string {
+	address := fmt.Sprintf("%s.%s", r.Type, r.Name)
+	if r.Mode == "data" {
+		address = "data." + address
+	}
+	if r.Module != "" {
+		address = r.Module + "." + address
+	}
+	return address
+}
+
+// instanceAddress returns the resource address with the count or for_each instance key
+func (r *Resource) instanceAddress(key any) string {
+	switch k := key.(type) {
+	case nil:
+		return r.address()
+	case string:
+		return fmt.Sprintf("%s[%q]", r.address(), k)
+	case float64:
+		return fmt.Sprintf("%s[%d]", r.address(), int(k))
+	default:
+		return fmt.Sprintf("%s[%v]", r.address(), k)
+	}
+}
@@ -0,0 +1,191 @@
+package state
+
+import (
+	"os"
+	"testing"
+
+	"github.com/envtio/base/integ"
+	"github.com/envtio/base/integ/redact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestState_Outputs(t *testing.T) {
+	s := Load(t, "../fixtures/terraform-state/terraform-output-all.tfstate")
+	assert.Equal(t, 4, s.Version)
+	assert.Equal(t, "1.8.2", s.TerraformVersion)
+
+	// same queries as TestTerraformOutputJMES against the applied terraform-output-all fixture
+	outputs := s.OutputSnapshot(t)
+	for query, expectedValue := range map[string]any{
+		"our_star":                           "Sun",
+		"stars[2]":                           "Betelgeuse",
+		"constellations.Taurus":              "Aldebaran",
+		"constellations.keys(@) | sort(@)":   []any{"Gemini", "Scorpio", "Taurus", "Virgo"},
+		"constellations.values(@) | sort(@)": []any{"Aldebaran", "Antares", "Pollux", "Spica"},
+		"lower(our_star)":                    "sun",
+		"max(values(magnitudes))":            0.96,
+	} {
+		t.Run(query, func(t *testing.T) {
+			value, err := outputs.Search(query)
+			require.NoError(t, err)
+			assert.Equal(t, expectedValue, value)
+		})
+	}
+
+	var bound struct {
+		OurStar string   `jmes:"our_star"`
+		Stars   []string `jmes:"stars"`
+	}
+	outputs.Bind(t, &bound)
+	assert.Equal(t, "Sun", bound.OurStar)
+	assert.Len(t, bound.Stars, 3)
+}
+
+func TestState_Resources(t *testing.T) {
+	s := Load(t, "../fixtures/terraform-state/resources.tfstate")
+
+	var addresses []string
+	for _, ri := range s.ResourceInstances() {
+		addresses = append(addresses, ri.Address)
+	}
+	assert.Equal(t, []string{
+		"aws_s3_bucket.catalog",
+		"aws_s3_object.star[0]",
+		"aws_s3_object.star[1]",
+		"data.aws_caller_identity.current",
+		`module.constellations.aws_ssm_parameter.brightest["Gemini"]`,
+		`module.constellations.aws_ssm_parameter.brightest["Taurus"]`,
+	}, addresses)
+
+	ri, ok := s.Resource("aws_s3_object.star[1]")
+	require.True(t, ok)
+	assert.Equal(t, "stars/rigel.json", ri.Attributes["key"])
+	assert.Equal(t, "list", ri.Resource.Each)
+
+	attributes, err := s.Attributes("data.aws_caller_identity.current")
+	require.NoError(t, err)
+	assert.Equal(t, "123456789012", attributes["account_id"])
+
+	_, err = s.Attributes("aws_s3_bucket.missing")
+	assert.Error(t, err)
+
+	value, err := s.SearchAttributes("aws_s3_bucket.catalog", "tags.Environment")
+	require.NoError(t, err)
+	assert.Equal(t, "test", value)
+
+	// the value of the parameter is a sensitive attribute
+	attributes, err = s.Attributes(`module.constellations.aws_ssm_parameter.brightest["Gemini"]`)
+	require.NoError(t, err)
+	assert.Equal(t, "***", attributes["value"])
+	assert.Equal(t, "String", attributes["type"])
+	attributes, err = s.RevealAttributes(t, `module.constellations.aws_ssm_parameter.brightest["Gemini"]`)
+	require.NoError(t, err)
+	assert.Equal(t, "Pollux", attributes["value"])
+	assert.Equal(t, "brightest ***", redact.String("brightest Pollux"))
+
+	assert.True(t, s.Outputs["api_key"].Sensitive)
+	outputs := s.OutputSnapshot(t)
+	assert.True(t, outputs.IsSensitive("api_key"))
+	assert.Equal(t, "***", outputs.String(t, "api_key"))
+	assert.Equal(t, "s3cr3t", outputs.RevealSensitive(t, "api_key"))
+}
+
+func TestState_Search(t *testing.T) {
+	s := Load(t, "../fixtures/terraform-state/resources.tfstate")
+	for query, expectedValue := range map[string]any{
+		"outputs.bucket.arn":                   "arn:aws:s3:::star-catalog",
+		"outputs.api_key":                      "***",
+		`resources."aws_s3_bucket.catalog".id`: "star-catalog",
+		`resources."module.constellations.aws_ssm_parameter.brightest[\"Taurus\"]".value`: "Aldebaran",
+		`resources."module.constellations.aws_ssm_parameter.brightest[\"Gemini\"]".value`: "***",
+		"resources.* | [?bucket == 'star-catalog'].key | sort(@)":                         []any{"stars/rigel.json", "stars/sirius.json"},
+	} {
+		t.Run(query, func(t *testing.T) {
+			value, err := s.Search(query)
+			require.NoError(t, err)
+			assert.Equal(t, expectedValue, value)
+		})
+	}
+
+	integ.Assert(t, s.OutputValues(), []integ.Assertion{
+		{
+			Path:    "bucket",
+			Matcher: integ.ObjectLike(map[string]any{"name": "star-catalog"}),
+		},
+	})
+}
+
+func TestState_SensitiveAttributes(t *testing.T) {
+	s, err := Parse([]byte(`{
+		"version": 4,
+		"resources": [{
+			"mode": "managed",
+			"type": "aws_db_instance",
+			"name": "this",
+			"instances": [{
+				"attributes": {
+					"password": "s3cr3t",
+					"port": 5432,
+					"users": [{"name": "app", "password": "app-s3cr3t"}],
+					"tags": {"Token": "t0k3n", "Environment": "test"}
+				},
+				"sensitive_attributes": [
+					[{"type": "get_attr", "value": "password"}],
+					[{"type": "get_attr", "value": "users"}, {"type": "index", "value": {"value": 0, "type": "number"}}, {"type": "get_attr", "value": "password"}],
+					[{"type": "get_attr", "value": "tags"}, {"type": "index", "value": {"value": "Token", "type": "string"}}],
+					[{"type": "get_attr", "value": "missing"}]
+				]
+			}]
+		}]
+	}`))
+	require.NoError(t, err)
+	attributes, err := s.Attributes("aws_db_instance.this")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"password": "***",
+		"port":     float64(5432),
+		"users":    []any{map[string]any{"name": "app", "password": "***"}},
+		"tags":     map[string]any{"Token": "***", "Environment": "test"},
+	}, attributes)
+
+	// the state is not modified by masking
+	attributes, err = s.RevealAttributes(t, "aws_db_instance.this")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", attributes["password"])
+	assert.Equal(t, "user *** token ***", redact.String("user app-s3cr3t token t0k3n"))
+
+	_, err = Parse([]byte(`{"version": 4, "resources": [{"mode": "managed", "type": "aws_db_instance", "name": "this",
+		"instances": [{"attributes": {}, "sensitive_attributes": [[{"type": "splat"}]]}]}]}`))
+	assert.ErrorContains(t, err, "aws_db_instance.this: unsupported sensitive attribute step type 'splat'")
+}
+
+func TestState_LoadE(t *testing.T) {
+	dir := t.TempDir()
+	data, err := os.ReadFile("../fixtures/terraform-state/terraform-output-all.tfstate")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dir+"/"+StateFileName, data, 0644))
+
+	s, err := LoadE(dir)
+	require.NoError(t, err, "Expected state to load from working dir")
+	assert.Equal(t, "Sun", s.OutputValues()["our_star"])
+
+	_, err = LoadE(t.TempDir())
+	assert.Error(t, err, "Expected error due to missing state file")
+
+	stackDir := t.TempDir()
+	require.NoError(t, os.WriteFile(stackDir+"/terraform-output-all.tfstate", data, 0644))
+	s, err = LoadE(stackDir)
+	require.NoError(t, err, "Expected LocalBackend stack state to load from working dir")
+	assert.Equal(t, "Sun", s.OutputValues()["our_star"])
+
+	require.NoError(t, os.WriteFile(stackDir+"/other.tfstate", data, 0644))
+	_, err = LoadE(stackDir)
+	assert.ErrorContains(t, err, "multiple state files")
+
+	_, err = Parse([]byte(`{"version": 3, "modules": []}`))
+	assert.ErrorContains(t, err, "unsupported state version 3")
+
+	_, err = Parse([]byte(`{`))
+	assert.Error(t, err)
+}
@@ -4,13 +4,16 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/envtio/base/integ/redact"
 	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/jmespath/go-jmespath"
 
 	"github.com/stretchr/testify/require"
 )
 
-// TerraformOutputJMES calls terraform output, searches values using JMESPath and converts to the desired type. This fails the test on any errors
+// terratestLogger masks sensitive output values
+var terratestLogger = redact.Logger
+
+// TerraformOutputJMES searches the cached terraform outputs values using JMESPath and converts to the desired type. This fails the test on any errors
 func TerraformOutputJMES[T any](t *testing.T, terraformOptions *terraform.Options, query string) T {
 	value := TerraformOutputJMESAny(t, terraformOptions, query)
 	// Go type assertions fail on this, so we use JSON marshalling
@@ -26,26 +29,22 @@ func TerraformOutputJMES[T any](t *testing.T, terraformOptions *terraform.Option
 	return result
 }
 
-// TerraformOutputJMESAny calls terraform output and searches values using JMESPath. This fails the test on any errors
+// TerraformOutputJMESAny searches the cached terraform outputs values using JMESPath. This fails the test on any errors
 func TerraformOutputJMESAny(t *testing.T, terraformOptions *terraform.Options, query string) interface{} {
 	result, err := TerraformOutputJMESAnyE(t, terraformOptions, query)
 	require.NoError(t, err)
 	return result
 }
 
-// TerraformOutputJMESAnyE calls terraform output and searches values using JMESPath.
+// TerraformOutputJMESAnyE searches the cached terraform outputs (see LoadOutputsE) using JMESPath.
+// Custom functions registered with RegisterJMESPathFunction are available in the query.
 func TerraformOutputJMESAnyE(t *testing.T, terraformOptions *terraform.Options, query string) (interface{}, error) {
-	p, err := jmespath.Compile(query)
-	if err != nil {
-		return nil, err
-	}
-	outputMap, err := terraform.OutputForKeysE(t, terraformOptions, nil)
-	if err != nil {
+	if _, err := CompileJMESPath(query); err != nil {
 		return nil, err
 	}
-	value, err := p.Search(outputMap)
+	outputs, err := LoadOutputsE(t, terraformOptions)
 	if err != nil {
 		return nil, err
 	}
-	return value, nil
+	return outputs.Search(query)
 }
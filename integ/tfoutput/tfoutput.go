@@ -0,0 +1,203 @@
+// Package tfoutput loads the outputs of a Terraform working dir once per version of its local state,
+// the cache is shared by the integ and integ/aws helpers.
+package tfoutput
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/envtio/base/integ/redact"
+	loggers "github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// terratestLogger masks sensitive output values
+var terratestLogger = redact.Logger
+
+// Output is an output of `terraform output -json`.
+type Output struct {
+	Value     any  `json:"value"`
+	Sensitive bool `json:"sensitive"`
+}
+
+// Snapshot is the outputs of a Terraform working dir at a version of its local state.
+type Snapshot struct {
+	Dir     string            // Absolute path of the working dir, empty if the snapshot was parsed
+	Outputs map[string]Output // Outputs by name, including the raw values of sensitive outputs
+
+	state      fingerprint
+	mu         sync.Mutex
+	registered map[*testing.T]bool // Tests the sensitive outputs are registered for, see register
+}
+
+// fingerprint identifies a version of the local state files
+type fingerprint struct {
+	modTime time.Time
+	size    int64
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[string]*Snapshot{}
+)
+
+// LoadE runs `terraform output -json` once and returns the cached snapshot on subsequent calls,
+// the leaves of sensitive outputs are registered for redaction in logs until the test completes.
+//
+// The snapshot is reloaded when the local state changes (i.e. after apply),
+// call Invalidate after applying with a remote backend.
+func LoadE(t *testing.T, terraformOptions *terraform.Options) (*Snapshot, error) {
+	key := cacheKey(terraformOptions)
+	state := localStateFingerprint(terraformOptions)
+
+	cacheMu.Lock()
+	snapshot, ok := cache[key]
+	cacheMu.Unlock()
+	if ok && snapshot.state == state {
+		snapshot.register(t)
+		return snapshot, nil
+	}
+	// parallel tests use distinct working dirs, so the lock is not held while running terraform output
+	snapshot, err := loadE(t, terraformOptions)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Dir, snapshot.state = key, state
+	cacheMu.Lock()
+	cache[key] = snapshot
+	cacheMu.Unlock()
+	snapshot.register(t)
+	return snapshot, nil
+}
+
+// register registers the values of the sensitive outputs for redaction until the test completes,
+// once per test for the many output lookups of a validator
+func (s *Snapshot) register(t *testing.T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.registered[t] {
+		return
+	}
+	if s.registered == nil {
+		s.registered = map[*testing.T]bool{}
+	}
+	s.registered[t] = true
+	// registered before the values, so it runs after they are released
+	t.Cleanup(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.registered, t)
+	})
+	for _, o := range s.Outputs {
+		if o.Sensitive {
+			redact.RegisterValue(t, o.Value)
+		}
+	}
+}
+
+// loadE runs `terraform output -json` without logging the output values,
+// which include the raw values of sensitive outputs.
+func loadE(t *testing.T, terraformOptions *terraform.Options) (*Snapshot, error) {
+	options := *terraformOptions
+	options.Logger = loggers.Discard
+	out, err := terraform.OutputJsonE(t, &options, "")
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := Parse([]byte(out))
+	if err != nil {
+		return nil, err
+	}
+	masked, err := json.Marshal(snapshot.MaskedValues())
+	if err != nil {
+		return nil, err
+	}
+	terratestLogger.Logf(t, "Loaded %d terraform output(s) from %s: %s", len(snapshot.Outputs), terraformOptions.TerraformDir, masked)
+	return snapshot, nil
+}
+
+// Parse parses the output of `terraform output -json`.
+func Parse(data []byte) (*Snapshot, error) {
+	var outputs map[string]Output
+	if err := json.Unmarshal(data, &outputs); err != nil {
+		return nil, fmt.Errorf("error parsing terraform output: %v", err)
+	}
+	if outputs == nil {
+		outputs = map[string]Output{}
+	}
+	return &Snapshot{Outputs: outputs}, nil
+}
+
+// Invalidate removes the cached snapshot of the Terraform working dir.
+func Invalidate(terraformOptions *terraform.Options) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	delete(cache, cacheKey(terraformOptions))
+}
+
+// Values returns the raw output values by name.
+func (s *Snapshot) Values() map[string]any {
+	values := make(map[string]any, len(s.Outputs))
+	for name, o := range s.Outputs {
+		values[name] = o.Value
+	}
+	return values
+}
+
+// MaskedValues returns the output values by name, the values of sensitive outputs are masked.
+func (s *Snapshot) MaskedValues() map[string]any {
+	values := make(map[string]any, len(s.Outputs))
+	for name, o := range s.Outputs {
+		if o.Sensitive {
+			values[name] = redact.MaskValue(o.Value)
+		} else {
+			values[name] = o.Value
+		}
+	}
+	return values
+}
+
+// Sensitive returns the sorted names of the sensitive outputs.
+func (s *Snapshot) Sensitive() []string {
+	var names []string
+	for name, o := range s.Outputs {
+		if o.Sensitive {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func cacheKey(terraformOptions *terraform.Options) string {
+	dir, err := filepath.Abs(terraformOptions.TerraformDir)
+	if err != nil {
+		return terraformOptions.TerraformDir
+	}
+	return dir
+}
+
+// localStateFingerprint returns the zero fingerprint if there is no local state.
+//
+// Test apps configure the LocalBackend path as `<stack name>.tfstate`, so all `*.tfstate` files in the working dir are considered.
+func localStateFingerprint(terraformOptions *terraform.Options) fingerprint {
+	stateFiles, _ := filepath.Glob(filepath.Join(terraformOptions.TerraformDir, "*.tfstate"))
+	var fp fingerprint
+	for _, stateFile := range stateFiles {
+		info, err := os.Stat(stateFile)
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(fp.modTime) {
+			fp.modTime = info.ModTime()
+		}
+		fp.size += info.Size()
+	}
+	return fp
+}
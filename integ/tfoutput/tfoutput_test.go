@@ -0,0 +1,76 @@
+package tfoutput
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/envtio/base/integ/redact"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	snapshot, err := Parse([]byte(`{
+		"our_star": {"sensitive": false, "type": "string", "value": "Sun"},
+		"jwt": {"sensitive": true, "type": ["object", {"secret_key": "string"}], "value": {"secret_key": "s3cr3t-key"}}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"jwt"}, snapshot.Sensitive())
+	assert.Equal(t, map[string]any{
+		"our_star": "Sun",
+		"jwt":      map[string]any{"secret_key": "s3cr3t-key"},
+	}, snapshot.Values())
+	assert.Equal(t, map[string]any{
+		"our_star": "Sun",
+		"jwt":      map[string]any{"secret_key": "***"},
+	}, snapshot.MaskedValues())
+
+	_, err = Parse([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestLoadE_Cache(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.tfstate"), []byte("{}"), 0644))
+	options := &terraform.Options{TerraformDir: dir}
+
+	// the cached snapshot of the current local state is returned without running terraform
+	cached := &Snapshot{Outputs: map[string]Output{
+		"jwt":      {Value: map[string]any{"secret_key": "s3cr3t-key", "issuer": "issuer-name"}, Sensitive: true},
+		"our_star": {Value: "Sirius"},
+	}, state: localStateFingerprint(options)}
+	cacheMu.Lock()
+	cache[cacheKey(options)] = cached
+	cacheMu.Unlock()
+	t.Cleanup(func() { Invalidate(options) })
+
+	t.Run("load", func(t *testing.T) {
+		snapshot, err := LoadE(t, options)
+		require.NoError(t, err)
+		assert.Same(t, cached, snapshot)
+		assert.Equal(t, "*** issued by *** for Sirius", redact.String("s3cr3t-key issued by issuer-name for Sirius"), "Expected sensitive leaves to be masked")
+
+		// cache hits of the same test do not register again
+		_, err = LoadE(t, options)
+		require.NoError(t, err)
+		cached.mu.Lock()
+		assert.Len(t, cached.registered, 1)
+		cached.mu.Unlock()
+	})
+	cached.mu.Lock()
+	assert.Empty(t, cached.registered, "Expected the test to be removed after it completes")
+	cached.mu.Unlock()
+	assert.Equal(t, "s3cr3t-key", redact.String("s3cr3t-key"), "Expected sensitive leaves to be unregistered after the test")
+
+	// apply changes the local state
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.tfstate"), []byte(`{"serial": 2}`), 0644))
+	assert.NotEqual(t, cached.state, localStateFingerprint(options), "Expected the fingerprint to change with the local state")
+
+	Invalidate(options)
+	cacheMu.Lock()
+	_, ok := cache[cacheKey(options)]
+	cacheMu.Unlock()
+	assert.False(t, ok, "Expected the snapshot to be removed")
+}
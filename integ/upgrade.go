@@ -0,0 +1,157 @@
+package integ
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	util "github.com/envtio/base/integ/aws"
+	"github.com/envtio/base/integ/plan"
+	"github.com/envtio/base/integ/redact"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/hashicorp/go-multierror"
+)
+
+// UpgradeFromEnvVar runs every scenario without Upgrade in upgrade mode from the published library version,
+// i.e. `UPGRADE_FROM_VERSION=0.1.0 make fifo-queue`
+const UpgradeFromEnvVar = "UPGRADE_FROM_VERSION"
+
+// Upgrade deploys the app with a published version of the library and upgrades it to the local `lib/`, see Run.
+//
+//	integ.Upgrade{
+//		From:   "0.1.0",
+//		Expect: []plan.Expectation{plan.ExpectNoReplace(), plan.ExpectOnlyAttributes("tags.*")},
+//	}
+type Upgrade struct {
+	From   string             // Published version of util.DefaultPackageName the app is deployed with, i.e. `0.1.0` or `latest`
+	Expect []plan.Expectation // Expectations of the plan of the upgrade, defaults to plan.ExpectNoDelete()
+}
+
+// upgradeName is the Day-2 step name of the upgrade stages `upgrade_app`, `plan_upgrade`, `apply_upgrade` and `validate_upgrade`
+const upgradeName = "upgrade"
+
+func (u Upgrade) expect() []plan.Expectation {
+	if len(u.Expect) == 0 {
+		return []plan.Expectation{plan.ExpectNoDelete()}
+	}
+	return u.Expect
+}
+
+// dependencies returns the synth dependencies of the published library
+func (u Upgrade) dependencies() map[string]string {
+	return map[string]string{util.DefaultPackageName: u.From}
+}
+
+// upgrade returns the Upgrade of the scenario, or of UPGRADE_FROM_VERSION, nil if not in upgrade mode.
+// Scenarios with AllStacks do not support upgrades, UPGRADE_FROM_VERSION is ignored for them.
+func (s Scenario) upgrade(t *testing.T) *Upgrade {
+	if s.Upgrade != nil {
+		return s.Upgrade
+	}
+	from := os.Getenv(UpgradeFromEnvVar)
+	if from == "" {
+		return nil
+	}
+	if s.AllStacks {
+		terratestLogger.Logf(t, "The '%s' environment variable is set, but upgrades are not supported with AllStacks, so skipping the upgrade of %s", UpgradeFromEnvVar, s.App)
+		return nil
+	}
+	return &Upgrade{From: from}
+}
+
+// runUpgrade re-synths the app deployed with the published library against the local `lib/`, checks and reports the
+// plan, applies it and validates the upgraded app
+func (s Scenario) runUpgrade(t *testing.T, u Upgrade, tfWorkingDir, awsRegion string, env map[string]string) {
+	step := Day2Step{Name: upgradeName}
+	test_structure.RunTestStage(t, step.SynthStage(), func() {
+		s.synth(t, tfWorkingDir, env, s.Variables, nil)
+	})
+	test_structure.RunTestStage(t, step.PlanStage(), func() {
+		terraformOptions := util.LoadTerraformOptions(t, tfWorkingDir)
+		upgradePlan := terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+		report := NewUpgradeReport(t.Name(), s.App, u.From, plan.Changes(upgradePlan), plan.CheckE(upgradePlan, u.expect()...))
+		if path, err := report.WriteE(reportDir()); err != nil {
+			t.Errorf("failed to write upgrade report: %v", err)
+		} else {
+			terratestLogger.Logf(t, "Wrote upgrade report %s", path)
+		}
+		plan.Check(t, upgradePlan, u.expect()...)
+	})
+	test_structure.RunTestStage(t, step.ApplyStage(), func() {
+		util.DeployUsingTerraform(t, tfWorkingDir, s.RetryableErrors)
+	})
+	if s.Validate != nil {
+		test_structure.RunTestStage(t, step.ValidateStage(), func() {
+			s.Validate(t, tfWorkingDir, awsRegion)
+		})
+	}
+}
+
+// UpgradeReport lists the resource changes of upgrading the library under a deployed app.
+type UpgradeReport struct {
+	Test    string          `json:"test"`             // Name of the test
+	App     string          `json:"app"`              // Name of the test app
+	From    string          `json:"from"`             // Published library version the app was deployed with
+	Time    time.Time       `json:"time"`             // Time of the upgrade plan
+	Passed  bool            `json:"passed"`           // Whether all upgrade expectations were met
+	Errors  []string        `json:"errors,omitempty"` // Unmet upgrade expectations
+	Changes []UpgradeChange `json:"changes"`          // Resource changes caused by the upgrade, sorted by address
+}
+
+// UpgradeChange is a resource change caused by the upgrade.
+type UpgradeChange struct {
+	Action     string   `json:"action"`               // One of plan.ActionCreate, plan.ActionUpdate, plan.ActionDelete, plan.ActionReplace
+	Address    string   `json:"address"`              // Resource address, i.e. `aws_sqs_queue.dlq`
+	Type       string   `json:"type"`                 // Resource type, i.e. `aws_sqs_queue`
+	Attributes []string `json:"attributes,omitempty"` // Changed attributes of updates and replacements, i.e. `timeout: 3 => 10`
+}
+
+// NewUpgradeReport returns the report of the upgrade plan changes and the combined errors of the upgrade expectations.
+func NewUpgradeReport(testName, app, from string, changes []plan.Change, expectErr error) UpgradeReport {
+	report := UpgradeReport{
+		Test:    testName,
+		App:     app,
+		From:    from,
+		Time:    time.Now(),
+		Passed:  expectErr == nil,
+		Changes: []UpgradeChange{},
+	}
+	if merr, ok := expectErr.(*multierror.Error); ok {
+		for _, err := range merr.Errors {
+			report.Errors = append(report.Errors, err.Error())
+		}
+	} else if expectErr != nil {
+		report.Errors = []string{expectErr.Error()}
+	}
+	for _, c := range changes {
+		uc := UpgradeChange{Action: c.Action, Address: c.Address, Type: c.Type}
+		if c.Action == plan.ActionUpdate || c.Action == plan.ActionReplace {
+			for _, ac := range plan.AttributeChanges(c.Resource) {
+				uc.Attributes = append(uc.Attributes, ac.String())
+			}
+		}
+		report.Changes = append(report.Changes, uc)
+	}
+	return report
+}
+
+// WriteE writes the report as JSON to `<dir>/<test name>.upgrade.json` and returns the path of the report.
+func (r UpgradeReport) WriteE(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error marshalling upgrade report: %v", err)
+	}
+	path := filepath.Join(dir, reportFileName(r.Test)+".upgrade.json")
+	// attribute values may contain sensitive values
+	if err := os.WriteFile(path, []byte(redact.String(string(data))), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
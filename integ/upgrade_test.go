@@ -0,0 +1,96 @@
+package integ
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/envtio/base/integ/plan"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScenario_Upgrade(t *testing.T) {
+	t.Setenv(UpgradeFromEnvVar, "")
+	assert.Nil(t, Scenario{App: "fifo-queue"}.upgrade(t))
+
+	t.Setenv(UpgradeFromEnvVar, "0.1.0")
+	assert.Equal(t, &Upgrade{From: "0.1.0"}, Scenario{App: "fifo-queue"}.upgrade(t))
+	// the scenario overrides the env var
+	assert.Equal(t, &Upgrade{From: "latest"}, Scenario{App: "fifo-queue", Upgrade: &Upgrade{From: "latest"}}.upgrade(t))
+	// the env var is ignored for scenarios with AllStacks
+	assert.Nil(t, Scenario{App: "multi-stack", AllStacks: true}.upgrade(t))
+	assert.Equal(t, map[string]string{"@envtio/base": "0.1.0"}, Upgrade{From: "0.1.0"}.dependencies())
+}
+
+// upgradePlan replaces the dlq and updates the timeout of the handler
+var upgradePlan = &terraform.PlanStruct{ResourceChangesMap: map[string]*tfjson.ResourceChange{
+	"aws_sqs_queue.dlq": {
+		Address: "aws_sqs_queue.dlq",
+		Type:    "aws_sqs_queue",
+		Change: &tfjson.Change{
+			Actions: tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate},
+			Before:  map[string]any{"name": "dlq"},
+			After:   map[string]any{"name": "dlq.fifo"},
+		},
+	},
+	"aws_lambda_function.handler": {
+		Address: "aws_lambda_function.handler",
+		Type:    "aws_lambda_function",
+		Change: &tfjson.Change{
+			Actions: tfjson.Actions{tfjson.ActionUpdate},
+			Before:  map[string]any{"timeout": float64(3)},
+			After:   map[string]any{"timeout": float64(10)},
+		},
+	},
+	"aws_iam_role.role": {
+		Address: "aws_iam_role.role",
+		Type:    "aws_iam_role",
+		Change:  &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionNoop}},
+	},
+}}
+
+func TestUpgrade_Expect(t *testing.T) {
+	// replacements fail by default
+	assert.Error(t, plan.CheckE(upgradePlan, Upgrade{From: "0.1.0"}.expect()...))
+	assert.NoError(t, plan.CheckE(upgradePlan, Upgrade{
+		From:   "0.1.0",
+		Expect: []plan.Expectation{plan.ExpectOnly(plan.ChangeSet{Update: []string{"aws_lambda_function"}, Replace: []string{"aws_sqs_queue.*"}})},
+	}.expect()...))
+}
+
+func TestUpgradeReport(t *testing.T) {
+	report := NewUpgradeReport("TestDlqQueue/upgrade", "dlq-queue", "0.1.0",
+		plan.Changes(upgradePlan), plan.CheckE(upgradePlan, plan.ExpectNoReplace(), plan.ExpectOnlyAttributes("memory_size")))
+
+	assert.False(t, report.Passed)
+	assert.Equal(t, []string{
+		"unexpected replace of 'aws_sqs_queue.dlq'",
+		"unexpected update of 'aws_lambda_function.handler' attribute(s) timeout",
+	}, report.Errors)
+	assert.Equal(t, []UpgradeChange{
+		{Action: plan.ActionUpdate, Address: "aws_lambda_function.handler", Type: "aws_lambda_function", Attributes: []string{"timeout: 3 => 10"}},
+		{Action: plan.ActionReplace, Address: "aws_sqs_queue.dlq", Type: "aws_sqs_queue", Attributes: []string{`name: "dlq" => "dlq.fifo"`}},
+	}, report.Changes)
+
+	dir := t.TempDir()
+	path, err := report.WriteE(dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "TestDlqQueue_upgrade.upgrade.json"), path)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var written UpgradeReport
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, "0.1.0", written.From)
+	assert.Len(t, written.Changes, 2)
+}
+
+func TestUpgradeReport_NoChanges(t *testing.T) {
+	report := NewUpgradeReport("TestFifoQueue", "fifo-queue", "0.1.0", nil, nil)
+	assert.True(t, report.Passed)
+	assert.Empty(t, report.Errors)
+	assert.NotNil(t, report.Changes)
+}